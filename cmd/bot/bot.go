@@ -1,96 +1,682 @@
 package bot
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
-	"os"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/todoist"
 	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/helpers"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/jobs"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/reactions"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/store"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/worker"
 )
 
 const (
-	DISCORD_TOKEN = "DISCORD_TOKEN"
-	PROJECT_NAME  = "News"
+	RETRY_INTERVAL = 10 * time.Second
+	// INTENTS are the reactions to follow and the guilds, whose channels
+	// fill the state the channel names are read from.
+	INTENTS = discordgo.IntentGuildMessageReactions | discordgo.IntentsGuilds
 )
 
 var (
-	ErrTokenNotProvided      = errors.New("DISCORD_TOKEN must be provided by env var")
-	ErrApiKeyNotProvided     = errors.New("API_KEY must be provided by env var")
+	ErrTokenNotProvided      = errors.New("discord token must be provided")
+	ErrSinkNotProvided       = errors.New("a sink must be provided")
+	ErrUnknownSink           = errors.New("unknown sink")
 	ErrCouldNotRetrieveTitle = errors.New("could not retrieve title")
+	ErrNoLinkFound           = errors.New("no link found")
+	ErrShutdownTimeout       = errors.New("shutdown timeout exceeded")
 )
 
 type Bot struct {
-	Todo    todoist.Todoist
+	Token string
+	// Client fetches the pages of the links, http.DefaultClient when nil.
+	Client *http.Client
+	// Sinks are the places news are saved to by name. A reaction saves to
+	// the sinks of its rule, to all of them when the rule names none.
+	Sinks         map[string]sink.TaskSink
+	Reactions     reactions.Mapping
+	Store         *store.Store
+	Pool          *worker.Pool
+	Journal       *jobs.Journal
+	CloseOnRemove bool
+	// ShutdownTimeout is how long queued reactions are waited for on shutdown.
+	ShutdownTimeout time.Duration
+	// ReactionTimeout bounds the processing of a reaction, it is retried
+	// later when exceeded. No limit when zero.
+	ReactionTimeout time.Duration
+
 	session *discordgo.Session
+	work    context.Context
+	// channels caches the names of the channels missing from the state.
+	channelsMutex sync.Mutex
+	channels      map[string]string
+}
+
+// sinkNames returns the sinks rule saves to, sorted so they are always
+// processed in the same order.
+func (b *Bot) sinkNames(rule reactions.Rule) (names []string) {
+	if len(rule.Sinks) > 0 {
+		return rule.Sinks
+	}
+	for name := range b.Sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// ensureSink checks sinkName, the sink a job was queued for, is configured.
+func (b *Bot) ensureSink(sinkName string) (err error) {
+	if _, ok := b.Sinks[sinkName]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownSink, sinkName)
+	}
+	return
 }
 
-func (b *Bot) sendErrorMessageToChannel(channelId, errMessage string) {
+// hasCreateReaction tells if a reaction still saves the message to the sink.
+func (b *Bot) hasCreateReaction(messageReactions []*discordgo.MessageReactions, sinkName string) bool {
+	for _, reaction := range messageReactions {
+		if reaction.Emoji == nil || reaction.Count == 0 {
+			continue
+		}
+		rule, ok := b.Reactions.Lookup(reaction.Emoji.ID, reaction.Emoji.Name)
+		if ok && rule.IsCreate() && slices.Contains(b.sinkNames(rule), sinkName) {
+			return true
+		}
+	}
+	return false
+}
+
+func sendErrorMessageToChannel(session *discordgo.Session, channelId, errMessage string) {
 	log.Println("error:", errMessage)
-	b.session.ChannelMessageSendComplex(channelId, &discordgo.MessageSend{
+	session.ChannelMessageSendComplex(channelId, &discordgo.MessageSend{
 		Content: errMessage,
 		Flags:   discordgo.MessageFlagsEphemeral,
 	})
 }
 
-func (b *Bot) processMessage(message, emoji string) (err error) {
-	if emoji == "😍" || emoji == "👌" || emoji == "👍" || emoji == "✅" {
-		title := ""
+// splitAlreadyRead takes the news already read out of err, rest is what is
+// left of it.
+func splitAlreadyRead(err error) (read []*sink.AlreadyReadError, rest error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	var others []error
+	for _, err := range errs {
+		var readErr *sink.AlreadyReadError
+		if errors.As(err, &readErr) {
+			read = append(read, readErr)
+			continue
+		}
+		others = append(others, err)
+	}
+	if len(read) == 0 {
+		return nil, err
+	}
+	return read, errors.Join(others...)
+}
+
+// alreadyReadReply tells when the news were read, the url is left out for a
+// single news.
+func alreadyReadReply(read []*sink.AlreadyReadError) string {
+	if len(read) == 1 {
+		return fmt.Sprintf("already read on %s", read[0].Date)
+	}
+	lines := make([]string, 0, len(read))
+	for _, readErr := range read {
+		lines = append(lines, readErr.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replyAlreadyRead answers message with the news of it already read.
+func (b *Bot) replyAlreadyRead(ctx context.Context, message *discordgo.Message, read []*sink.AlreadyReadError) {
+	if len(read) == 0 {
+		return
+	}
+	content := alreadyReadReply(read)
+	log.Printf("message %s: %s", message.ID, content)
+	if b.session == nil {
+		return
+	}
+	_, err := b.session.ChannelMessageSendReply(message.ChannelID, content, message.Reference(), discordgo.WithContext(ctx))
+	if err != nil {
+		log.Println("error: reply already read:", err)
+	}
+}
+
+func messageUrls(message *discordgo.Message) []string {
+	embedUrls := make([]string, 0, len(message.Embeds))
+	for _, embed := range message.Embeds {
+		embedUrls = append(embedUrls, embed.URL)
+	}
+	return helpers.ExtractUrls(message.Content, embedUrls...)
+}
 
-		// TODO: use go routine
-		title, err = helpers.GetTitleFromUrl(message)
+// findByUrl looks the news up as shared, then under its canonical url and
+// last under the url of its page, the one it was saved with, as short links
+// only resolve once fetched.
+func (b *Bot) findByUrl(ctx context.Context, sinkName string, url string) (item sink.Item, err error) {
+	item, err = b.Sinks[sinkName].FindByUrl(ctx, url)
+	if err != sink.ErrNotFound {
+		return
+	}
+	canonical, canonicalErr := helpers.Canonicalize(url)
+	if canonicalErr == nil && canonical != url {
+		item, err = b.Sinks[sinkName].FindByUrl(ctx, canonical)
+		if err != sink.ErrNotFound {
+			return
+		}
+	}
+	page, pageErr := helpers.GetPage(ctx, b.Client, url)
+	if pageErr != nil || page.Url == url || page.Url == canonical {
+		return
+	}
+	return b.Sinks[sinkName].FindByUrl(ctx, page.Url)
+}
+
+// ownedByAnother tells if another message than messageId saved the task,
+// the message skipped as a duplicate of it must not remove it.
+func (b *Bot) ownedByAnother(sinkName, messageId, taskId string) bool {
+	for _, record := range b.Store.ByTask(sinkName, taskId) {
+		if record.MessageId != messageId && record.Status == store.StatusCreated {
+			return true
+		}
+	}
+	return false
+}
+
+// removeTodo removes the todo the message saved. The store is authoritative,
+// the sink is only looked up when the message has no record and the todo
+// found is left alone when another message saved it.
+func (b *Bot) removeTodo(ctx context.Context, sinkName string, message *discordgo.Message, url string, close bool) (parentTaskId string, err error) {
+	record, ok := b.Store.Get(message.ID, url, sinkName)
+	if ok && record.Status != store.StatusCreated {
+		return
+	}
+
+	if !ok || record.TaskId == "" {
+		item, err := b.findByUrl(ctx, sinkName, url)
 		if err != nil {
-			return fmt.Errorf("%s for %s", ErrCouldNotRetrieveTitle.Error(), message)
+			if err == sink.ErrNotFound {
+				return "", nil
+			}
+			return "", err
+		}
+		if b.ownedByAnother(sinkName, message.ID, item.Id) {
+			return "", nil
+		}
+		record = store.Record{
+			GuildId:      message.GuildID,
+			ChannelId:    message.ChannelID,
+			MessageId:    message.ID,
+			Url:          url,
+			Sink:         sinkName,
+			TaskId:       item.Id,
+			ParentTaskId: item.ParentId,
 		}
-		err = b.Todo.CreateTodo(title, message)
+	}
+
+	err = b.closeOrDelete(ctx, sinkName, record.TaskId, close)
+	if err != nil {
+		return
+	}
+
+	record.Status = store.StatusDeleted
+	if close {
+		record.Status = store.StatusClosed
+	}
+	return record.ParentTaskId, b.Store.Put(record)
+}
+
+// closeOrDelete falls back on deleting the item when the sink can't mark it
+// as done. An item already removed from the sink is done.
+func (b *Bot) closeOrDelete(ctx context.Context, sinkName string, taskId string, close bool) (err error) {
+	taskSink := b.Sinks[sinkName]
+	if completer, ok := taskSink.(sink.Completer); ok && close {
+		err = completer.Complete(ctx, taskId)
+	} else {
+		err = taskSink.Delete(ctx, taskId)
+	}
+	if errors.Is(err, sink.ErrNotFound) {
+		return nil
 	}
 	return
 }
 
-func (b *Bot) messageReactionAdd(session *discordgo.Session, reaction *discordgo.MessageReactionAdd) {
-	emoji := reaction.Emoji.Name
-	channelId := reaction.ChannelID
-	b.session = session
-	message, err := session.ChannelMessage(channelId, reaction.MessageID)
+// removeTodos removes the todo of every link, then the parent todo of the
+// groups they belonged to.
+func (b *Bot) removeTodos(ctx context.Context, sinkName string, message *discordgo.Message, urls []string, close bool) error {
+	var errs []error
+	parents := map[string]bool{}
+	for _, url := range urls {
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+		parentTaskId, err := b.removeTodo(ctx, sinkName, message, url, close)
+		errs = append(errs, err)
+		if parentTaskId != "" {
+			parents[parentTaskId] = true
+		}
+	}
+	for parentTaskId := range parents {
+		errs = append(errs, b.closeOrDelete(ctx, sinkName, parentTaskId, close))
+	}
+	return errors.Join(errs...)
+}
+
+// startDate is the first day a news can be scheduled on for rule.
+func startDate(rule reactions.Rule) string {
+	return time.Now().AddDate(0, 0, rule.SnoozeDays).Format("2006-01-02")
+}
+
+// channelName returns the name of the channel, from the state or else asked
+// to discord once. Its id is returned when it can't be found.
+func (b *Bot) channelName(ctx context.Context, channelId string) string {
+	if b.session == nil {
+		return channelId
+	}
+	channel, err := b.session.State.Channel(channelId)
+	if err == nil && channel.Name != "" {
+		return channel.Name
+	}
+
+	b.channelsMutex.Lock()
+	defer b.channelsMutex.Unlock()
+	if name, ok := b.channels[channelId]; ok {
+		return name
+	}
+	channel, err = b.session.Channel(channelId, discordgo.WithContext(ctx))
+	if err != nil || channel.Name == "" {
+		return channelId
+	}
+	if b.channels == nil {
+		b.channels = map[string]string{}
+	}
+	b.channels[channelId] = channel.Name
+	return channel.Name
+}
+
+// itemTags tags a news with the emoji used and the channel it was shared in.
+func itemTags(emoji *discordgo.Emoji, channel string) (tags []string) {
+	for _, tag := range []string{emoji.Name, channel} {
+		tag = strings.Join(strings.Fields(tag), "-")
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return
+}
+
+// newItem returns what every news saved by a reaction shares: where it was
+// shared, the reaction and how the rule schedules it.
+func (b *Bot) newItem(ctx context.Context, message *discordgo.Message, emoji *discordgo.Emoji, rule reactions.Rule) sink.Item {
+	channel := b.channelName(ctx, message.ChannelID)
+	return sink.Item{
+		Channel:  channel,
+		Emoji:    emoji.Name,
+		Tags:     itemTags(emoji, channel),
+		DueDate:  startDate(rule),
+		Priority: rule.Priority,
+	}
+}
+
+func (b *Bot) createTodo(ctx context.Context, sinkName string, message *discordgo.Message, url string, base sink.Item) (err error) {
+	record, ok := b.Store.Get(message.ID, url, sinkName)
+	if ok && record.Status == store.StatusCreated {
+		return sink.ErrAlreadyExist
+	}
+
+	record = store.Record{
+		GuildId:   message.GuildID,
+		ChannelId: message.ChannelID,
+		MessageId: message.ID,
+		Url:       url,
+		Sink:      sinkName,
+		Status:    store.StatusFailed,
+	}
+
+	page, err := helpers.GetPage(ctx, b.Client, url)
+	if err != nil {
+		b.Store.Put(record)
+		return fmt.Errorf("%s for %s", ErrCouldNotRetrieveTitle.Error(), url)
+	}
+
+	base.Title, base.Url = page.Title, page.Url
+	item, err := b.Sinks[sinkName].Create(ctx, base)
+	read, err := splitAlreadyRead(err)
+	if len(read) > 0 {
+		b.replyAlreadyRead(ctx, message, read)
+		return nil
+	}
 	if err != nil {
-		log.Println("error on retrieve message:", err)
+		if err != sink.ErrAlreadyExist {
+			b.Store.Put(record)
+		}
 		return
 	}
 
-	err = b.processMessage(message.Content, emoji)
+	record.Status = store.StatusCreated
+	record.TaskId = item.Id
+	return b.Store.Put(record)
+}
+
+// groupTitle names a group after the text of the message, falling back on
+// the title of its first link.
+func groupTitle(message *discordgo.Message, urls []string, firstTitle string) string {
+	text := message.Content
+	for _, url := range urls {
+		text = strings.ReplaceAll(text, url, "")
+	}
+	text = strings.Trim(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0], " \t<>|[]():-")
+	if text == "" {
+		return firstTitle
+	}
+	return text
+}
+
+func (b *Bot) createGroup(ctx context.Context, sinkName string, message *discordgo.Message, urls []string, base sink.Item) (err error) {
+	var errs []error
+	var children []sink.Item
+	// shared are the links of the message saved under each canonical url
+	shared := map[string][]string{}
+	for _, url := range urls {
+		record, ok := b.Store.Get(message.ID, url, sinkName)
+		if ok && record.Status == store.StatusCreated {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		page, err := helpers.GetPage(ctx, b.Client, url)
+		if err != nil {
+			b.Store.Put(store.Record{GuildId: message.GuildID, ChannelId: message.ChannelID, MessageId: message.ID, Url: url, Sink: sinkName, Status: store.StatusFailed})
+			errs = append(errs, fmt.Errorf("%s for %s", ErrCouldNotRetrieveTitle.Error(), url))
+			continue
+		}
+		if _, ok := shared[page.Url]; !ok {
+			children = append(children, sink.Item{Title: page.Title, Url: page.Url, Tags: base.Tags})
+		}
+		shared[page.Url] = append(shared[page.Url], url)
+	}
+	if len(children) == 0 {
+		return errors.Join(errs...)
+	}
+
+	base.Title, base.Children = groupTitle(message, urls, children[0].Title), children
+	parent, err := b.Sinks[sinkName].Create(ctx, base)
+	read, err := splitAlreadyRead(err)
+	b.replyAlreadyRead(ctx, message, read)
+	if err != nil && err != sink.ErrAlreadyExist {
+		errs = append(errs, err)
+	}
+
+	created := map[string]sink.Item{}
+	for _, child := range parent.Children {
+		created[child.Url] = child
+	}
+	for _, child := range children {
+		createdChild, ok := created[child.Url]
+		if !ok && (err == nil || err == sink.ErrAlreadyExist) {
+			// skipped by the sink as it already exists or was read
+			continue
+		}
+
+		for _, url := range shared[child.Url] {
+			record := store.Record{
+				GuildId:   message.GuildID,
+				ChannelId: message.ChannelID,
+				MessageId: message.ID,
+				Url:       url,
+				Sink:      sinkName,
+				Status:    store.StatusFailed,
+			}
+			if ok {
+				record.Status = store.StatusCreated
+				record.TaskId = createdChild.Id
+				record.ParentTaskId = parent.Id
+			}
+			errs = append(errs, b.Store.Put(record))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// processMessage applies the rule of emoji on sinkName, its errors are
+// prefixed by the sink name.
+func (b *Bot) processMessage(ctx context.Context, message *discordgo.Message, emoji *discordgo.Emoji, sinkName string) (err error) {
+	rule, ok := b.Reactions.Lookup(emoji.ID, emoji.Name)
+	if !ok {
+		return
+	}
+	err = b.ensureSink(sinkName)
 	if err != nil {
-		if err != todoist.ErrAlreadyExist {
-			b.sendErrorMessageToChannel(channelId, err.Error())
-			return
+		return
+	}
+
+	urls := messageUrls(message)
+	if len(urls) == 0 {
+		if rule.IsCreate() {
+			return fmt.Errorf("%w in %s", ErrNoLinkFound, message.Content)
 		}
+		return
+	}
+
+	err = b.applyRule(ctx, sinkName, message, emoji, urls, rule)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sinkName, err)
 	}
+	return
 }
 
-func (b *Bot) messageReactionRemove(session *discordgo.Session, reaction *discordgo.MessageReactionRemove) {
-	emoji := reaction.Emoji.Name
+func (b *Bot) applyRule(ctx context.Context, sinkName string, message *discordgo.Message, emoji *discordgo.Emoji, urls []string, rule reactions.Rule) (err error) {
+	switch rule.Action {
+	case reactions.ActionMarkRead:
+		return b.removeTodos(ctx, sinkName, message, urls, true)
+	case reactions.ActionDrop:
+		return b.removeTodos(ctx, sinkName, message, urls, false)
+	}
 
-	log.Println(emoji)
+	base := b.newItem(ctx, message, emoji, rule)
+	if len(urls) > 1 {
+		return b.createGroup(ctx, sinkName, message, urls, base)
+	}
+	err = b.createTodo(ctx, sinkName, message, urls[0], base)
+	if err == sink.ErrAlreadyExist {
+		return nil
+	}
+	return
 }
 
-func (b *Bot) Start() (err error) {
-	token := os.Getenv(DISCORD_TOKEN)
-	apiKey := os.Getenv(todoist.API_KEY)
+// isPermanent tells if retrying err later has no chance to succeed.
+func isPermanent(err error) bool {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		status := restErr.Response.StatusCode
+		return status == http.StatusNotFound || status == http.StatusForbidden
+	}
+	return errors.Is(err, ErrNoLinkFound) ||
+		errors.Is(err, ErrUnknownSink) ||
+		errors.Is(err, sink.ErrPermanent)
+}
 
-	if token == "" {
-		return ErrTokenNotProvided
+func (b *Bot) processJob(ctx context.Context, job jobs.Job) (err error) {
+	message, err := b.session.ChannelMessage(job.ChannelId, job.MessageId, discordgo.WithContext(ctx))
+	if err != nil {
+		return
+	}
+
+	message.GuildID = job.GuildId
+	emoji := &discordgo.Emoji{ID: job.EmojiId, Name: job.EmojiName}
+	if job.Kind == jobs.KindRemove {
+		return b.processRemoval(ctx, message, emoji, message.Reactions, job.Sink)
+	}
+	return b.processMessage(ctx, message, emoji, job.Sink)
+}
+
+// reactionContext gives the processing of a reaction ReactionTimeout to
+// finish, it is cancelled with ctx on shutdown.
+func (b *Bot) reactionContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.ReactionTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, b.ReactionTimeout)
+}
+
+func (b *Bot) runJob(ctx context.Context, job jobs.Job) {
+	reactionCtx, cancel := b.reactionContext(ctx)
+	err := b.processJob(reactionCtx, job)
+	cancel()
+	if err == nil {
+		err = b.Journal.Complete(job.Id)
+		if err != nil {
+			log.Println("error on complete job", job.Id, err)
+		}
+		return
+	}
+
+	if ctx.Err() != nil {
+		// interrupted by shutdown, it stays pending for the next start
+		b.Journal.Release(job.Id)
+		return
+	}
+
+	if isPermanent(err) {
+		err = jobs.Permanent(err)
+	}
+	failed, journalErr := b.Journal.Fail(job.Id, err)
+	if journalErr != nil {
+		log.Println("error on fail job", job.Id, journalErr)
+		return
+	}
+
+	if failed.Status == jobs.StatusDead {
+		sendErrorMessageToChannel(b.session, job.ChannelId, fmt.Sprintf("%s (gave up after %d attempts)", err, failed.Attempts))
+		return
 	}
-	if apiKey == "" {
-		return ErrApiKeyNotProvided
+	log.Printf("job %s failed (attempt %d), retry at %s: %s", job.Id, failed.Attempts, failed.NextAttempt.Format(time.RFC3339), err)
+}
+
+// dispatch runs the job on the worker pool, reactions on the same message
+// are serialized so they can't create the same todo twice. A job refused by
+// a full pool stays in the journal and is picked again by the retry loop.
+func (b *Bot) dispatch(job jobs.Job) {
+	ctx := b.work
+	err := b.Pool.Submit(job.MessageId, func() { b.runJob(ctx, job) })
+	if err != nil {
+		log.Println("job", job.Id, "postponed:", err)
+		b.Journal.Release(job.Id)
 	}
+}
 
-	err = b.Todo.Init(PROJECT_NAME)
+func (b *Bot) enqueue(job jobs.Job) {
+	added, err := b.Journal.Add(job)
 	if err != nil {
+		sendErrorMessageToChannel(b.session, job.ChannelId, err.Error())
 		return
 	}
+	b.dispatch(added)
+}
 
-	dg, err := discordgo.New(fmt.Sprintf("Bot %s", token))
+func (b *Bot) retryLoop(ctx context.Context) {
+	ticker := time.NewTicker(RETRY_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			due, err := b.Journal.Claim(now)
+			if err != nil {
+				log.Println("error on claim jobs:", err)
+				continue
+			}
+			for _, job := range due {
+				b.dispatch(job)
+			}
+		}
+	}
+}
+
+// enqueueReaction queues a job per sink of the rule of the reaction, so a
+// sink failing is retried on its own without saving twice to the others.
+func (b *Bot) enqueueReaction(kind jobs.Kind, reaction *discordgo.MessageReaction) {
+	rule, ok := b.Reactions.Lookup(reaction.Emoji.ID, reaction.Emoji.Name)
+	if !ok {
+		return
+	}
+	for _, name := range b.sinkNames(rule) {
+		b.enqueue(jobs.Job{
+			Kind:      kind,
+			GuildId:   reaction.GuildID,
+			ChannelId: reaction.ChannelID,
+			MessageId: reaction.MessageID,
+			EmojiId:   reaction.Emoji.ID,
+			EmojiName: reaction.Emoji.Name,
+			Sink:      name,
+		})
+	}
+}
+
+func (b *Bot) messageReactionAdd(session *discordgo.Session, reaction *discordgo.MessageReactionAdd) {
+	b.enqueueReaction(jobs.KindAdd, reaction.MessageReaction)
+}
+
+// processRemoval removes the todos from sinkName unless another reaction
+// still saves the message to it.
+func (b *Bot) processRemoval(ctx context.Context, message *discordgo.Message, emoji *discordgo.Emoji, messageReactions []*discordgo.MessageReactions, sinkName string) (err error) {
+	rule, ok := b.Reactions.Lookup(emoji.ID, emoji.Name)
+	if !ok || !rule.IsCreate() {
+		return
+	}
+	err = b.ensureSink(sinkName)
+	if err != nil || b.hasCreateReaction(messageReactions, sinkName) {
+		return
+	}
+
+	err = b.removeTodos(ctx, sinkName, message, messageUrls(message), b.CloseOnRemove)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sinkName, err)
+	}
+	return
+}
+
+func (b *Bot) messageReactionRemove(session *discordgo.Session, reaction *discordgo.MessageReactionRemove) {
+	b.enqueueReaction(jobs.KindRemove, reaction.MessageReaction)
+}
+
+// Run connects the bot and processes reactions until ctx is done, then shuts
+// down gracefully: no more reactions are received, queued ones get up to
+// ShutdownTimeout to finish and the work still in flight is cancelled.
+func (b *Bot) Run(ctx context.Context) (err error) {
+	if b.Token == "" {
+		return ErrTokenNotProvided
+	}
+	if len(b.Sinks) == 0 {
+		return ErrSinkNotProvided
+	}
+	for _, name := range b.Reactions.Sinks() {
+		if _, ok := b.Sinks[name]; !ok {
+			return fmt.Errorf("%w %q in reactions", ErrUnknownSink, name)
+		}
+	}
+
+	dg, err := discordgo.New(fmt.Sprintf("Bot %s", b.Token))
 	if err != nil {
 		return
 	}
@@ -98,13 +684,46 @@ func (b *Bot) Start() (err error) {
 	dg.AddHandler(b.messageReactionAdd)
 	dg.AddHandler(b.messageReactionRemove)
 
-	dg.Identify.Intents = discordgo.IntentGuildMessageReactions
+	dg.Identify.Intents = INTENTS
+
+	work, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+	b.work = work
+	b.session = dg
 
 	err = dg.Open()
 	if err != nil {
 		return
 	}
-	defer dg.Close()
 
-	return
+	retryDone := make(chan struct{})
+	go func() {
+		b.retryLoop(ctx)
+		close(retryDone)
+	}()
+
+	log.Println("bot is now running.")
+	<-ctx.Done()
+	<-retryDone
+
+	log.Println("bot is shutting down.")
+	return b.shutdown(dg, cancelWork)
+}
+
+func (b *Bot) shutdown(session io.Closer, cancelWork context.CancelFunc) error {
+	var errs []error
+	err := session.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("close discord session: %w", err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.ShutdownTimeout)
+	defer cancel()
+	err = b.Pool.Drain(ctx)
+	if err != nil {
+		cancelWork()
+		errs = append(errs, fmt.Errorf("%w: reactions in flight were cancelled and will be retried on next start", ErrShutdownTimeout))
+		b.Pool.Drain(context.Background())
+	}
+	return errors.Join(errs...)
 }
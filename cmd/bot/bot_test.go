@@ -1,10 +1,113 @@
 package bot
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/github"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/todoist"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/reactions"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/store"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/worker"
 )
 
+type fakeSession struct {
+	closed bool
+}
+
+func (f *fakeSession) Close() error {
+	f.closed = true
+	return nil
+}
+
+// slowSink waits for the deadline of the reaction to create an item.
+type slowSink struct {
+	sink.TaskSink
+}
+
+func (s slowSink) Create(ctx context.Context, item sink.Item) (sink.Item, error) {
+	<-ctx.Done()
+	return item, ctx.Err()
+}
+
+// readSink answers the news under /read were read already, the others are
+// saved to its memory.
+type readSink struct {
+	*sink.Memory
+}
+
+func (s readSink) Create(ctx context.Context, item sink.Item) (sink.Item, error) {
+	isRead := func(url string) error {
+		if strings.Contains(url, "/read") {
+			return &sink.AlreadyReadError{Url: url, Date: "2026-01-02"}
+		}
+		return nil
+	}
+	if len(item.Children) == 0 {
+		if err := isRead(item.Url); err != nil {
+			return sink.Item{}, err
+		}
+		return s.Memory.Create(ctx, item)
+	}
+
+	var errs []error
+	var children []sink.Item
+	for _, child := range item.Children {
+		if err := isRead(child.Url); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		children = append(children, child)
+	}
+	item.Children = children
+	created, err := s.Memory.Create(ctx, item)
+	return created, errors.Join(append(errs, err)...)
+}
+
+// newChannelsSession knows the channel 111 as golang from its state, discord
+// answers any other channel is named rust. lookups counts the questions.
+func newChannelsSession(t testing.TB) (session *discordgo.Session, lookups *int) {
+	t.Helper()
+	lookups = new(int)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		*lookups++
+		fmt.Fprintf(rw, `{"id": %q, "name": "rust"}`, strings.TrimPrefix(req.URL.Path, "/channels/"))
+	}))
+	t.Cleanup(server.Close)
+	endpoint := discordgo.EndpointChannels
+	discordgo.EndpointChannels = server.URL + "/channels/"
+	t.Cleanup(func() { discordgo.EndpointChannels = endpoint })
+
+	session, err := discordgo.New("Bot XXXX")
+	if err != nil {
+		t.Fatalf("can't create session: %q", err)
+	}
+	session.Client = server.Client()
+	session.State.GuildAdd(&discordgo.Guild{ID: "guild", Channels: []*discordgo.Channel{{ID: "111", GuildID: "guild", Name: "golang"}}})
+	return session, lookups
+}
+
+// recordSink keeps the last item created in its memory.
+type recordSink struct {
+	*sink.Memory
+	created sink.Item
+}
+
+func (s *recordSink) Create(ctx context.Context, item sink.Item) (sink.Item, error) {
+	s.created = item
+	return s.Memory.Create(ctx, item)
+}
+
 func TestBot(t *testing.T) {
 	assertError := func(t testing.TB, got, want error) {
 		t.Helper()
@@ -12,26 +115,44 @@ func TestBot(t *testing.T) {
 			t.Fatal("didn't get an error but wanted one")
 		}
 
-		if got != want {
+		if !errors.Is(got, want) {
 			t.Errorf("got %q, want %q", got, want)
 		}
 	}
 
-	bot := Bot{}
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got error [%s] but did not want one", err)
+		}
+	}
+
+	pages := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(rw, "<html><head><title>Title of %s</title></head></html>", req.URL.Path)
+	}))
+	defer pages.Close()
+
+	messageStore, err := store.Open("")
+	if err != nil {
+		t.Fatalf("can't open store: %q", err)
+	}
+	memory := sink.NewMemory()
+	bot := Bot{Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"memory": memory}}
 
 	t.Run("It should handle error on token not provided", func(t *testing.T) {
-		err := bot.Start()
+		err := bot.Run(context.Background())
 		assertError(t, err, ErrTokenNotProvided)
 	})
 
-	t.Run("It should handle error on api key not provided", func(t *testing.T) {
-		t.Setenv(DISCORD_TOKEN, "XXXX")
-		err := bot.Start()
-		assertError(t, err, ErrApiKeyNotProvided)
+	t.Run("It should handle error on sink not provided", func(t *testing.T) {
+		tokenBot := Bot{Token: "XXXX"}
+		err := tokenBot.Run(context.Background())
+		assertError(t, err, ErrSinkNotProvided)
 	})
 
 	t.Run("It should handle error on invalid token", func(t *testing.T) {
-		err := bot.Start()
+		invalidBot := Bot{Token: "XXXX", Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"memory": memory}}
+		err := invalidBot.Run(context.Background())
 
 		if err == nil {
 			t.Fatal("didn't get an error but wanted one")
@@ -39,10 +160,10 @@ func TestBot(t *testing.T) {
 	})
 
 	t.Run("It should not create a todo from discord message when it is not a link", func(t *testing.T) {
-		message := "foobar"
-		emoji := "👌"
-		wantedErrorMessage := fmt.Sprintf("%s for %s", ErrCouldNotRetrieveTitle.Error(), message)
-		err := bot.processMessage(message, emoji)
+		message := &discordgo.Message{ID: "1", Content: "foobar"}
+		emoji := &discordgo.Emoji{Name: "👌"}
+		wantedErrorMessage := fmt.Sprintf("%s in %s", ErrNoLinkFound.Error(), message.Content)
+		err := bot.processMessage(context.Background(), message, emoji, "memory")
 
 		if err == nil {
 			t.Fatal("didn't get an error but wanted one")
@@ -53,11 +174,529 @@ func TestBot(t *testing.T) {
 	})
 
 	t.Run("It should do nothing on unknown emoji", func(t *testing.T) {
-		message := "foobar"
-		emoji := "😂"
-		err := bot.processMessage(message, emoji)
+		message := &discordgo.Message{ID: "1", Content: "foobar"}
+		emoji := &discordgo.Emoji{Name: "😂"}
+		err := bot.processMessage(context.Background(), message, emoji, "memory")
+		if err != nil {
+			t.Fatalf("got error [%s] but did not want one", err)
+		}
+	})
+
+	t.Run("It should detect remaining create reaction", func(t *testing.T) {
+		messageReactions := []*discordgo.MessageReactions{
+			{Count: 1, Emoji: &discordgo.Emoji{Name: "😂"}},
+			{Count: 2, Emoji: &discordgo.Emoji{Name: "👍🏾"}},
+		}
+		if !bot.hasCreateReaction(messageReactions, "memory") {
+			t.Fatal("should have found a create reaction")
+		}
+	})
+
+	t.Run("It should not detect create reaction when none remain", func(t *testing.T) {
+		messageReactions := []*discordgo.MessageReactions{
+			{Count: 1, Emoji: &discordgo.Emoji{Name: "😂"}},
+			{Count: 0, Emoji: &discordgo.Emoji{Name: "👍"}},
+		}
+		if bot.hasCreateReaction(messageReactions, "memory") {
+			t.Fatal("should not have found a create reaction")
+		}
+	})
+
+	t.Run("It should do nothing on removal of unknown emoji", func(t *testing.T) {
+		err := bot.processRemoval(context.Background(), &discordgo.Message{ID: "1", Content: "foobar"}, &discordgo.Emoji{Name: "😂"}, nil, "memory")
+		if err != nil {
+			t.Fatalf("got error [%s] but did not want one", err)
+		}
+	})
+
+	t.Run("It should keep todo when another create reaction remains", func(t *testing.T) {
+		messageReactions := []*discordgo.MessageReactions{
+			{Count: 1, Emoji: &discordgo.Emoji{Name: "✅"}},
+		}
+		err := bot.processRemoval(context.Background(), &discordgo.Message{ID: "1", Content: "foobar"}, &discordgo.Emoji{Name: "👍"}, messageReactions, "memory")
 		if err != nil {
 			t.Fatalf("got error [%s] but did not want one", err)
 		}
 	})
+
+	t.Run("It should name the channels a news is shared in", func(t *testing.T) {
+		session, lookups := newChannelsSession(t)
+		namedBot := Bot{session: session}
+		if INTENTS&discordgo.IntentsGuilds == 0 {
+			t.Fatal("the guilds intent is needed to fill the state with the channels")
+		}
+
+		if got := namedBot.channelName(context.Background(), "111"); got != "golang" {
+			t.Fatalf("got %q, want the name of the channel in the state", got)
+		}
+		for i := 0; i < 2; i++ {
+			if got := namedBot.channelName(context.Background(), "222"); got != "rust" {
+				t.Fatalf("got %q, want the name discord gives", got)
+			}
+		}
+		if *lookups != 1 {
+			t.Fatalf("got %d lookups, want the name cached after the first one", *lookups)
+		}
+	})
+
+	t.Run("It should route a news on the name of its channel", func(t *testing.T) {
+		session, _ := newChannelsSession(t)
+		record := &recordSink{Memory: sink.NewMemory()}
+		namedBot := Bot{Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"record": record}, session: session}
+
+		message := &discordgo.Message{ID: "14", ChannelID: "111", Content: pages.URL + "/routed"}
+		assertNoError(t, namedBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍🏽"}, "record"))
+		for _, route := range []todoist.Route{{Channel: "#golang", Section: "Go"}, {Emoji: "👍", Section: "Liked"}} {
+			if !route.Matches(record.created) {
+				t.Fatalf("route %+v should match %+v", route, record.created)
+			}
+		}
+	})
+
+	t.Run("It should tag and label a news with the name of its channel", func(t *testing.T) {
+		session, _ := newChannelsSession(t)
+		var labels []string
+		repository := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodPost {
+				rw.Write([]byte("[]"))
+				return
+			}
+			var issue struct {
+				Labels []string `json:"labels"`
+			}
+			json.NewDecoder(req.Body).Decode(&issue)
+			labels = issue.Labels
+			rw.Write([]byte(`{"number": 1}`))
+		}))
+		defer repository.Close()
+		record := &recordSink{Memory: sink.NewMemory()}
+		issues := &github.GitHub{Client: repository.Client(), BaseUrl: repository.URL, Repository: "owner/news"}
+		namedBot := Bot{Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"record": record, "github": issues}, session: session}
+
+		message := &discordgo.Message{ID: "15", ChannelID: "111", Content: pages.URL + "/tagged"}
+		for _, name := range []string{"record", "github"} {
+			assertNoError(t, namedBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, name))
+		}
+		if strings.Join(record.created.Tags, ",") != "👍,golang" {
+			t.Fatalf("got tags %v, want the emoji and the channel name", record.created.Tags)
+		}
+		if !slices.Contains(labels, "channel:golang") {
+			t.Fatalf("got labels %v, want the channel name", labels)
+		}
+	})
+
+	t.Run("It should create an item from the link of a message", func(t *testing.T) {
+		url := pages.URL + "/create"
+		message := &discordgo.Message{ID: "10", ChannelID: "channel", Content: "look " + url}
+		err := bot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "memory")
+		assertNoError(t, err)
+
+		item, err := memory.FindByUrl(context.Background(), url)
+		assertNoError(t, err)
+		if item.Title != "Title of /create" {
+			t.Fatalf("got title %q, want %q", item.Title, "Title of /create")
+		}
+		if strings.Join(item.Tags, ",") != "👍,channel" {
+			t.Fatalf("got tags %v, want the emoji and the channel", item.Tags)
+		}
+		if item.Emoji != "👍" || item.Channel != "channel" {
+			t.Fatalf("got emoji %q and channel %q, want the reaction and its channel", item.Emoji, item.Channel)
+		}
+		record, _ := messageStore.Get(message.ID, url, "memory")
+		if record.Status != store.StatusCreated || record.TaskId != item.Id {
+			t.Fatalf("unexpected record %+v", record)
+		}
+	})
+
+	t.Run("It should remove the item when the last create reaction is removed", func(t *testing.T) {
+		url := pages.URL + "/remove"
+		message := &discordgo.Message{ID: "11", Content: url}
+		assertNoError(t, bot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "memory"))
+
+		err := bot.processRemoval(context.Background(), message, &discordgo.Emoji{Name: "👍"}, nil, "memory")
+		assertNoError(t, err)
+
+		_, err = memory.FindByUrl(context.Background(), url)
+		assertError(t, err, sink.ErrNotFound)
+		record, _ := messageStore.Get(message.ID, url, "memory")
+		if record.Status != store.StatusDeleted {
+			t.Fatalf("got status %s, want %s", record.Status, store.StatusDeleted)
+		}
+	})
+
+	t.Run("It should complete the item when configured to close on remove", func(t *testing.T) {
+		url := pages.URL + "/close"
+		closeBot := Bot{Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"memory": memory}, CloseOnRemove: true}
+		message := &discordgo.Message{ID: "12", Content: url}
+		assertNoError(t, closeBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "memory"))
+
+		err := closeBot.processRemoval(context.Background(), message, &discordgo.Emoji{Name: "👍"}, nil, "memory")
+		assertNoError(t, err)
+
+		record, _ := messageStore.Get(message.ID, url, "memory")
+		if record.Status != store.StatusClosed {
+			t.Fatalf("got status %s, want %s", record.Status, store.StatusClosed)
+		}
+	})
+
+	t.Run("It should keep the item saved by another message sharing the same link", func(t *testing.T) {
+		url := pages.URL + "/shared"
+		first := &discordgo.Message{ID: "16", Content: url}
+		second := &discordgo.Message{ID: "17", Content: "again " + url}
+		assertNoError(t, bot.processMessage(context.Background(), first, &discordgo.Emoji{Name: "👍"}, "memory"))
+		assertNoError(t, bot.processMessage(context.Background(), second, &discordgo.Emoji{Name: "👍"}, "memory"))
+
+		assertNoError(t, bot.processRemoval(context.Background(), second, &discordgo.Emoji{Name: "👍"}, nil, "memory"))
+		if _, err := memory.FindByUrl(context.Background(), url); err != nil {
+			t.Fatalf("the item of the first message should be kept: %v", err)
+		}
+
+		assertNoError(t, bot.processRemoval(context.Background(), first, &discordgo.Emoji{Name: "👍"}, nil, "memory"))
+		_, err := memory.FindByUrl(context.Background(), url)
+		assertError(t, err, sink.ErrNotFound)
+	})
+
+	t.Run("It should return sink error on removal", func(t *testing.T) {
+		failing := sink.NewMemory()
+		failing.Err = todoist.ErrNotInitialized
+		failingBot := Bot{Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"memory": failing}}
+
+		err := failingBot.processRemoval(context.Background(), &discordgo.Message{ID: "6", Content: "https://foo.bar"}, &discordgo.Emoji{Name: "👍"}, nil, "memory")
+		if !errors.Is(err, todoist.ErrNotInitialized) {
+			t.Fatalf("got %v, want %v", err, todoist.ErrNotInitialized)
+		}
+	})
+
+	t.Run("It should match custom emoji by id", func(t *testing.T) {
+		mapping, err := reactions.New([]reactions.Rule{{Emoji: "123456789", Action: reactions.ActionDrop}})
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+		customBot := Bot{Reactions: mapping, Store: messageStore, Sinks: map[string]sink.TaskSink{"memory": memory}}
+		url := "https://foo.bar/custom"
+		memory.Create(context.Background(), sink.Item{Title: "foo", Url: url})
+
+		err = customBot.processMessage(context.Background(), &discordgo.Message{ID: "7", Content: url}, &discordgo.Emoji{ID: "123456789", Name: "custom"}, "memory")
+		assertNoError(t, err)
+		_, err = memory.FindByUrl(context.Background(), url)
+		assertError(t, err, sink.ErrNotFound)
+	})
+
+	t.Run("It should record failed item in store", func(t *testing.T) {
+		url := "http://127.0.0.1:1/article"
+		message := &discordgo.Message{ID: "2", ChannelID: "channel", GuildID: "guild", Content: "read " + url}
+		err := bot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "memory")
+		if err == nil {
+			t.Fatal("didn't get an error but wanted one")
+		}
+
+		record, ok := messageStore.Get(message.ID, url, "memory")
+		if !ok {
+			t.Fatal("record should have been stored")
+		}
+		if record.Status != store.StatusFailed || record.ChannelId != "channel" || record.GuildId != "guild" {
+			t.Fatalf("unexpected record %+v", record)
+		}
+	})
+
+	t.Run("It should not create twice an item already created", func(t *testing.T) {
+		message := &discordgo.Message{ID: "3", Content: "https://foo.bar"}
+		messageStore.Put(store.Record{MessageId: message.ID, Url: message.Content, Sink: "memory", TaskId: "12345", Status: store.StatusCreated})
+
+		err := bot.createTodo(context.Background(), "memory", message, message.Content, sink.Item{})
+		assertError(t, err, sink.ErrAlreadyExist)
+
+		err = bot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "memory")
+		if err != nil {
+			t.Fatalf("got error [%s] but did not want one", err)
+		}
+	})
+
+	t.Run("It should mark the stored item removed when the sink no longer has it", func(t *testing.T) {
+		message := &discordgo.Message{ID: "4", Content: "https://foo.bar"}
+		messageStore.Put(store.Record{MessageId: message.ID, Url: message.Content, Sink: "memory", TaskId: "12345", Status: store.StatusCreated})
+
+		err := bot.processRemoval(context.Background(), message, &discordgo.Emoji{Name: "👍"}, nil, "memory")
+		if err != nil {
+			t.Fatalf("didn't expect an error but got one: %v", err)
+		}
+		record, _ := messageStore.Get(message.ID, message.Content, "memory")
+		if record.Status != store.StatusDeleted {
+			t.Errorf("got %q, want %q", record.Status, store.StatusDeleted)
+		}
+	})
+
+	t.Run("It should do nothing on removal of an item already removed", func(t *testing.T) {
+		message := &discordgo.Message{ID: "5", Content: "https://foo.bar"}
+		messageStore.Put(store.Record{MessageId: message.ID, Url: message.Content, Sink: "memory", TaskId: "12345", Status: store.StatusDeleted})
+
+		err := bot.processRemoval(context.Background(), message, &discordgo.Emoji{Name: "👍"}, nil, "memory")
+		if err != nil {
+			t.Fatalf("got error [%s] but did not want one", err)
+		}
+	})
+
+	t.Run("It should handle every link of a message including embeds", func(t *testing.T) {
+		message := &discordgo.Message{
+			ID:      "8",
+			Content: "two links https://a.dev and <https://b.dev>",
+			Embeds:  []*discordgo.MessageEmbed{{URL: "https://c.dev"}, {URL: "https://a.dev"}},
+		}
+		for _, url := range []string{"https://a.dev", "https://b.dev", "https://c.dev"} {
+			messageStore.Put(store.Record{MessageId: message.ID, Url: url, Sink: "memory", TaskId: "12345", Status: store.StatusDeleted})
+		}
+
+		urls := messageUrls(message)
+		if len(urls) != 3 {
+			t.Fatalf("got %v, want 3 links", urls)
+		}
+		err := bot.processRemoval(context.Background(), message, &discordgo.Emoji{Name: "👍"}, nil, "memory")
+		if err != nil {
+			t.Fatalf("got error [%s] but did not want one", err)
+		}
+	})
+
+	t.Run("It should name a group after the message text or its first title", func(t *testing.T) {
+		urls := []string{"https://a.dev", "https://b.dev"}
+		message := &discordgo.Message{Content: "Weekly digest:\n- <https://a.dev>\n- https://b.dev"}
+		if got := groupTitle(message, urls, "A"); got != "Weekly digest" {
+			t.Fatalf("got %q, want %q", got, "Weekly digest")
+		}
+
+		message = &discordgo.Message{Content: "https://a.dev https://b.dev"}
+		if got := groupTitle(message, urls, "A"); got != "A" {
+			t.Fatalf("got %q, want %q", got, "A")
+		}
+	})
+
+	t.Run("It should create a group for a multi-link message", func(t *testing.T) {
+		groupSink := sink.NewMemory()
+		groupBot := Bot{Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"memory": groupSink}}
+		urls := []string{pages.URL + "/a", pages.URL + "/b"}
+		message := &discordgo.Message{ID: "9", Content: "Digest\n" + urls[0] + "\n" + urls[1]}
+
+		err := groupBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "memory")
+		assertNoError(t, err)
+
+		items, _ := groupSink.List(context.Background())
+		if len(items) != 3 || items[0].Title != "Digest" || items[1].ParentId != items[0].Id || items[2].ParentId != items[0].Id {
+			t.Fatalf("unexpected items %+v", items)
+		}
+		record, _ := messageStore.Get(message.ID, urls[1], "memory")
+		if record.ParentTaskId != items[0].Id {
+			t.Fatalf("record should point to parent %s but got %+v", items[0].Id, record)
+		}
+
+		err = groupBot.processRemoval(context.Background(), message, &discordgo.Emoji{Name: "👍"}, nil, "memory")
+		assertNoError(t, err)
+		items, _ = groupSink.List(context.Background())
+		if len(items) != 0 {
+			t.Fatalf("group should have been removed but got %+v", items)
+		}
+	})
+
+	t.Run("It should save the links of a message under their canonical url", func(t *testing.T) {
+		canonicalSink := sink.NewMemory()
+		canonicalBot := Bot{Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"memory": canonicalSink}}
+		urls := []string{pages.URL + "/same/?utm_source=rss", pages.URL + "/same#top", pages.URL + "/other"}
+		message := &discordgo.Message{ID: "12", Content: strings.Join(urls, " ")}
+
+		err := canonicalBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "memory")
+		assertNoError(t, err)
+
+		items, _ := canonicalSink.List(context.Background())
+		if len(items) != 3 || items[1].Url != pages.URL+"/same" {
+			t.Fatalf("the same page should be saved once under its canonical url, got %+v", items)
+		}
+		for _, url := range urls[:2] {
+			record, _ := messageStore.Get(message.ID, url, "memory")
+			if record.Status != store.StatusCreated || record.TaskId != items[1].Id {
+				t.Fatalf("unexpected record %+v for %s", record, url)
+			}
+		}
+
+		messageStore.Delete(message.ID, urls[0], "memory")
+		_, err = canonicalBot.removeTodo(context.Background(), "memory", message, urls[0], false)
+		assertNoError(t, err)
+		if _, err := canonicalSink.FindByUrl(context.Background(), pages.URL+"/same"); !errors.Is(err, sink.ErrNotFound) {
+			t.Fatalf("got %v, want %v", err, sink.ErrNotFound)
+		}
+	})
+
+	t.Run("It should find an item saved from a short link under the url of its page", func(t *testing.T) {
+		shortener := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.Redirect(rw, req, pages.URL+"/shortened", http.StatusMovedPermanently)
+		}))
+		defer shortener.Close()
+		// a store of its own, the ids of the memory sinks of the other tests collide
+		shortStore, _ := store.Open("")
+		shortSink := sink.NewMemory()
+		shortBot := Bot{Client: pages.Client(), Reactions: reactions.Default(), Store: shortStore, Sinks: map[string]sink.TaskSink{"memory": shortSink}}
+		url := shortener.URL + "/xyz"
+		message := &discordgo.Message{ID: "13", Content: url}
+		assertNoError(t, shortBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "memory"))
+
+		shortStore.Delete(message.ID, url, "memory")
+		_, err := shortBot.removeTodo(context.Background(), "memory", message, url, false)
+		assertNoError(t, err)
+		if _, err := shortSink.FindByUrl(context.Background(), pages.URL+"/shortened"); !errors.Is(err, sink.ErrNotFound) {
+			t.Fatalf("got %v, want %v", err, sink.ErrNotFound)
+		}
+	})
+
+	t.Run("It should save to every sink of the rule and report failures per sink", func(t *testing.T) {
+		todos, notes, links := sink.NewMemory(), sink.NewMemory(), sink.NewMemory()
+		notes.Err = errors.New("disk full")
+		mapping, _ := reactions.New([]reactions.Rule{
+			{Emoji: "👍", Action: reactions.ActionCreate},
+			{Emoji: "📚", Action: reactions.ActionCreate, Sinks: []string{"links"}},
+		})
+		fanOutBot := Bot{Reactions: mapping, Store: messageStore, Sinks: map[string]sink.TaskSink{"todos": todos, "notes": notes, "links": links}}
+		url := pages.URL + "/fan-out"
+		message := &discordgo.Message{ID: "10", Content: url}
+
+		// a reaction queues a job per sink of its rule
+		rule, _ := mapping.Lookup("", "👍")
+		var errs []error
+		for _, name := range fanOutBot.sinkNames(rule) {
+			errs = append(errs, fanOutBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, name))
+		}
+		err := errors.Join(errs...)
+		if err == nil || !strings.HasPrefix(err.Error(), "notes: ") || !errors.Is(err, notes.Err) {
+			t.Fatalf("got %v, want the notes failure", err)
+		}
+		for _, saved := range []sink.TaskSink{todos, links} {
+			if _, err := saved.FindByUrl(context.Background(), url); err != nil {
+				t.Fatalf("link should have been saved: %v", err)
+			}
+		}
+
+		notes.Err = nil
+		assertNoError(t, fanOutBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "notes"))
+		if items, _ := todos.List(context.Background()); len(items) != 1 {
+			t.Fatalf("retrying notes should not save twice to todos, got %+v", items)
+		}
+
+		messageReactions := []*discordgo.MessageReactions{{Emoji: &discordgo.Emoji{Name: "📚"}, Count: 1}}
+		for _, name := range fanOutBot.sinkNames(rule) {
+			assertNoError(t, fanOutBot.processRemoval(context.Background(), message, &discordgo.Emoji{Name: "👍"}, messageReactions, name))
+		}
+		if _, err := links.FindByUrl(context.Background(), url); err != nil {
+			t.Fatalf("link is still wanted by 📚: %v", err)
+		}
+		if _, err := todos.FindByUrl(context.Background(), url); !errors.Is(err, sink.ErrNotFound) {
+			t.Fatalf("got %v, want %v", err, sink.ErrNotFound)
+		}
+
+		err = fanOutBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "")
+		assertError(t, err, ErrUnknownSink)
+		if !isPermanent(err) {
+			t.Fatalf("%v should not be retried", err)
+		}
+
+		fanOutBot.Token = "XXXX"
+		fanOutBot.Sinks = map[string]sink.TaskSink{"todos": todos}
+		assertError(t, fanOutBot.Run(context.Background()), ErrUnknownSink)
+	})
+
+	t.Run("It should not fail on the news already read", func(t *testing.T) {
+		readBot := Bot{Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"read": readSink{sink.NewMemory()}}}
+		read, other := pages.URL+"/read", pages.URL+"/unread"
+
+		message := &discordgo.Message{ID: "12", Content: read}
+		assertNoError(t, readBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "read"))
+		if _, ok := messageStore.Get(message.ID, read, "read"); ok {
+			t.Fatal("a news already read should not be recorded as failed")
+		}
+
+		message = &discordgo.Message{ID: "13", Content: read + " " + other}
+		assertNoError(t, readBot.processMessage(context.Background(), message, &discordgo.Emoji{Name: "👍"}, "read"))
+		record, _ := messageStore.Get(message.ID, other, "read")
+		if record.Status != store.StatusCreated {
+			t.Fatalf("unexpected record %+v", record)
+		}
+		if _, ok := messageStore.Get(message.ID, read, "read"); ok {
+			t.Fatal("a news already read should not be recorded as failed")
+		}
+
+		got := alreadyReadReply([]*sink.AlreadyReadError{{Url: read, Date: "2026-01-02"}})
+		if got != "already read on 2026-01-02" {
+			t.Fatalf("got reply %q", got)
+		}
+	})
+
+	t.Run("It should give up a reaction after its deadline", func(t *testing.T) {
+		slowBot := Bot{Reactions: reactions.Default(), Store: messageStore, Sinks: map[string]sink.TaskSink{"slow": slowSink{sink.NewMemory()}}, ReactionTimeout: 10 * time.Millisecond}
+		ctx, cancel := slowBot.reactionContext(context.Background())
+		defer cancel()
+
+		message := &discordgo.Message{ID: "11", Content: pages.URL + "/slow"}
+		err := slowBot.processMessage(ctx, message, &discordgo.Emoji{Name: "👍"}, "slow")
+		assertError(t, err, context.DeadlineExceeded)
+		if isPermanent(err) {
+			t.Fatalf("%v should be retried", err)
+		}
+	})
+
+	t.Run("It should close the session and drain queued reactions on shutdown", func(t *testing.T) {
+		done := false
+		session := &fakeSession{}
+		poolBot := Bot{Pool: worker.New(1, 1), ShutdownTimeout: time.Second}
+		err := poolBot.Pool.Submit("message", func() { done = true })
+		if err != nil {
+			t.Fatalf("got error [%s] but did not want one", err)
+		}
+
+		err = poolBot.shutdown(session, func() {})
+		if err != nil {
+			t.Fatalf("got error [%s] but did not want one", err)
+		}
+		if !session.closed {
+			t.Fatal("session should have been closed")
+		}
+		if !done {
+			t.Fatal("queued reaction should have been processed")
+		}
+	})
+
+	t.Run("It should cancel work in flight when shutdown timeout is exceeded", func(t *testing.T) {
+		work, cancelWork := context.WithCancel(context.Background())
+		poolBot := Bot{Pool: worker.New(1, 1), ShutdownTimeout: 10 * time.Millisecond}
+		err := poolBot.Pool.Submit("message", func() { <-work.Done() })
+		if err != nil {
+			t.Fatalf("got error [%s] but did not want one", err)
+		}
+
+		err = poolBot.shutdown(&fakeSession{}, cancelWork)
+		if !errors.Is(err, ErrShutdownTimeout) {
+			t.Fatalf("got %v, want %v", err, ErrShutdownTimeout)
+		}
+	})
+
+	t.Run("It should not retry permanent errors", func(t *testing.T) {
+		permanent := []error{
+			fmt.Errorf("%w in foobar", ErrNoLinkFound),
+			todoist.ErrHttpRequestUnauthorized,
+			errors.Join(todoist.ErrProjectNotFound),
+			fmt.Errorf("todos: %w", sink.ErrNotSupported),
+			sink.NewPermanentError("wrong configuration"),
+			&discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusNotFound}},
+		}
+		for _, err := range permanent {
+			if !isPermanent(err) {
+				t.Fatalf("%v should be permanent", err)
+			}
+		}
+
+		transient := []error{
+			todoist.ErrHttpRequestDefault,
+			fmt.Errorf("%s for https://foo.bar", ErrCouldNotRetrieveTitle.Error()),
+			&discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusBadGateway}},
+		}
+		for _, err := range transient {
+			if isPermanent(err) {
+				t.Fatalf("%v should not be permanent", err)
+			}
+		}
+	})
 }
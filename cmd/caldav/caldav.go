@@ -0,0 +1,381 @@
+package caldav
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/httpapi"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/schedule"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+const (
+	STATUS_NEEDS_ACTION = "NEEDS-ACTION"
+	STATUS_COMPLETED    = "COMPLETED"
+)
+
+var (
+	ErrNotInitialized          = sink.NewPermanentError("caldav object not initialized, call init method")
+	ErrHttpRequestDefault      = httpapi.NewError("error on caldav server call", httpapi.ErrApiCall)
+	ErrHttpRequestUnauthorized = httpapi.NewError("unauthorized caldav access", httpapi.ErrUnauthorized)
+)
+
+var apiErrors = httpapi.Errors{
+	Default:      ErrHttpRequestDefault,
+	Unauthorized: ErrHttpRequestUnauthorized,
+	ByStatus: map[int]error{
+		http.StatusForbidden:          ErrHttpRequestUnauthorized,
+		http.StatusNotFound:           sink.ErrNotFound,
+		http.StatusPreconditionFailed: sink.ErrAlreadyExist,
+	},
+}
+
+var _ sink.TaskSink = &CalDAV{}
+var _ sink.Completer = &CalDAV{}
+
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter>
+</c:calendar-query>`
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Prop struct {
+				ETag         string `xml:"DAV: getetag"`
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// CalDAV saves news as VTODO in the calendar collection at CalendarUrl, for
+// instance https://cloud.example.com/remote.php/dav/calendars/me/news/.
+type CalDAV struct {
+	Client      *http.Client
+	CalendarUrl string
+	Username    string
+	Password    string
+	// MaxTodoPerDay and MaxDaysToLookUp default to schedule.MAX_PER_DAY and
+	// schedule.MAX_DAYS_TO_LOOK_UP when left empty.
+	MaxTodoPerDay   int
+	MaxDaysToLookUp int
+	SlotPerLink     bool
+
+	calendar *url.URL
+}
+
+// Init checks the calendar can be reached with the given credentials.
+func (c *CalDAV) Init(ctx context.Context) (err error) {
+	calendar, err := url.Parse(c.CalendarUrl)
+	if err != nil || calendar.Host == "" {
+		return fmt.Errorf("invalid calendar url %q", c.CalendarUrl)
+	}
+	if !strings.HasSuffix(calendar.Path, "/") {
+		calendar.Path += "/"
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	c.calendar = calendar
+
+	_, err = c.list(ctx)
+	if err != nil {
+		c.calendar = nil
+	}
+	return
+}
+
+func (c *CalDAV) do(ctx context.Context, method, href string, body string, headers map[string]string) (response *http.Response, err error) {
+	if c.calendar == nil {
+		return nil, ErrNotInitialized
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, href, strings.NewReader(body))
+	if err != nil {
+		return
+	}
+	if c.Username != "" || c.Password != "" {
+		request.SetBasicAuth(c.Username, c.Password)
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	return apiErrors.Do(c.Client, request)
+}
+
+func (c *CalDAV) href(uid string) string {
+	return c.calendar.ResolveReference(&url.URL{Path: url.PathEscape(uid) + ".ics"}).String()
+}
+
+// list returns every VTODO of the calendar.
+func (c *CalDAV) list(ctx context.Context) (todos []Todo, err error) {
+	if c.calendar == nil {
+		return nil, ErrNotInitialized
+	}
+	response, err := c.do(ctx, "REPORT", c.calendar.String(), calendarQuery, map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "1",
+	})
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	var result multistatus
+	err = xml.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid multistatus: %w", ErrHttpRequestDefault, err)
+	}
+
+	for _, resource := range result.Responses {
+		for _, propstat := range resource.Propstat {
+			todo, ok := DecodeTodo(propstat.Prop.CalendarData)
+			if !ok {
+				continue
+			}
+			href, err := c.calendar.Parse(resource.Href)
+			if err != nil {
+				continue
+			}
+			todo.Href = href.String()
+			todo.ETag = propstat.Prop.ETag
+			todos = append(todos, todo)
+		}
+	}
+	return
+}
+
+// put writes todo, creating it when it has no ETag yet.
+func (c *CalDAV) put(ctx context.Context, todo Todo) (err error) {
+	headers := map[string]string{"Content-Type": "text/calendar; charset=utf-8"}
+	if todo.ETag == "" {
+		headers["If-None-Match"] = "*"
+	} else {
+		headers["If-Match"] = todo.ETag
+	}
+	href := todo.Href
+	if href == "" {
+		href = c.href(todo.Uid)
+	}
+
+	response, err := c.do(ctx, http.MethodPut, href, todo.Encode(), headers)
+	if err != nil {
+		return
+	}
+	return response.Body.Close()
+}
+
+func newUid() (uid string, err error) {
+	random := make([]byte, 16)
+	_, err = rand.Read(random)
+	if err != nil {
+		return
+	}
+	return hex.EncodeToString(random), nil
+}
+
+func isOpen(todo Todo) bool {
+	return todo.Status != STATUS_COMPLETED && todo.Status != "CANCELLED"
+}
+
+func findOpenByUrl(todos []Todo, url string) *Todo {
+	for i, todo := range todos {
+		if url != "" && todo.Url == url && isOpen(todo) {
+			return &todos[i]
+		}
+	}
+	return nil
+}
+
+func (c *CalDAV) newTodo(item sink.Item, due string) (todo Todo, err error) {
+	uid, err := newUid()
+	if err != nil {
+		return
+	}
+	return Todo{
+		Uid:         uid,
+		Summary:     item.Title,
+		Url:         item.Url,
+		Description: item.Url,
+		Categories:  item.Channel,
+		Due:         due,
+		Status:      STATUS_NEEDS_ACTION,
+		Priority:    toIcalPriority(item.Priority),
+		RelatedTo:   item.ParentId,
+	}, nil
+}
+
+func (c *CalDAV) Create(ctx context.Context, item sink.Item) (created sink.Item, err error) {
+	todos, err := c.list(ctx)
+	if err != nil {
+		return
+	}
+	if findOpenByUrl(todos, item.Url) != nil {
+		return created, sink.ErrAlreadyExist
+	}
+
+	var children []sink.Item
+	for _, child := range item.Children {
+		if findOpenByUrl(todos, child.Url) == nil {
+			children = append(children, child)
+		}
+	}
+	if len(item.Children) > 0 && len(children) == 0 {
+		return created, sink.ErrAlreadyExist
+	}
+
+	slots := 1
+	if c.SlotPerLink && len(children) > 0 {
+		slots = len(children)
+	}
+	start, err := schedule.StartDate(item.DueDate)
+	if err != nil {
+		return
+	}
+	dueDate, err := schedule.Capacity{MaxPerDay: c.MaxTodoPerDay, MaxDaysToLookUp: c.MaxDaysToLookUp}.WithDefaults().FirstFreeDay(start, slots, func(date string) (count int, err error) {
+		for _, todo := range todos {
+			takesSlot := todo.RelatedTo == ""
+			if c.SlotPerLink {
+				takesSlot = todo.Url != ""
+			}
+			if isOpen(todo) && takesSlot && todo.item().DueDate == date {
+				count++
+			}
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	due, err := icalDate(dueDate)
+	if err != nil {
+		return
+	}
+
+	parent, err := c.newTodo(item, due)
+	if err != nil {
+		return
+	}
+	err = c.put(ctx, parent)
+	if err != nil {
+		return
+	}
+	created = parent.item()
+
+	for _, child := range children {
+		child.ParentId = parent.Uid
+		child.Priority = item.Priority
+		if child.Channel == "" {
+			child.Channel = item.Channel
+		}
+		todo, err := c.newTodo(child, due)
+		if err != nil {
+			return created, err
+		}
+		err = c.put(ctx, todo)
+		if err != nil {
+			return created, err
+		}
+		created.Children = append(created.Children, todo.item())
+	}
+	return
+}
+
+func (c *CalDAV) FindByUrl(ctx context.Context, url string) (item sink.Item, err error) {
+	todos, err := c.list(ctx)
+	if err != nil {
+		return
+	}
+	todo := findOpenByUrl(todos, url)
+	if todo == nil {
+		return item, sink.ErrNotFound
+	}
+	return todo.item(), nil
+}
+
+// withChildren returns the todo uid along with its subtasks.
+func (c *CalDAV) withChildren(ctx context.Context, uid string) (todos []Todo, err error) {
+	all, err := c.list(ctx)
+	if err != nil {
+		return
+	}
+	for _, todo := range all {
+		if todo.Uid == uid || todo.RelatedTo == uid {
+			todos = append(todos, todo)
+		}
+	}
+	if len(todos) == 0 {
+		return nil, sink.ErrNotFound
+	}
+	return
+}
+
+func (c *CalDAV) Delete(ctx context.Context, id string) (err error) {
+	todos, err := c.withChildren(ctx, id)
+	if err != nil {
+		return
+	}
+	var errs []error
+	for _, todo := range todos {
+		response, err := c.do(ctx, http.MethodDelete, todo.Href, "", map[string]string{"If-Match": todo.ETag})
+		if err == nil {
+			err = response.Body.Close()
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// update applies change to the todo id and its subtasks.
+func (c *CalDAV) update(ctx context.Context, id string, change func(todo *Todo)) (err error) {
+	todos, err := c.withChildren(ctx, id)
+	if err != nil {
+		return
+	}
+	var errs []error
+	for _, todo := range todos {
+		change(&todo)
+		errs = append(errs, c.put(ctx, todo))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *CalDAV) Complete(ctx context.Context, id string) (err error) {
+	return c.update(ctx, id, func(todo *Todo) {
+		todo.Status = STATUS_COMPLETED
+		todo.Completed = time.Now()
+	})
+}
+
+func (c *CalDAV) Reschedule(ctx context.Context, id string, dueDate string) (err error) {
+	due, err := icalDate(dueDate)
+	if err != nil {
+		return
+	}
+	return c.update(ctx, id, func(todo *Todo) {
+		todo.Due = due
+	})
+}
+
+func (c *CalDAV) List(ctx context.Context) (items []sink.Item, err error) {
+	todos, err := c.list(ctx)
+	if err != nil {
+		return
+	}
+	for _, todo := range todos {
+		items = append(items, todo.item())
+	}
+	return
+}
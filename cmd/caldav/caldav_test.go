@@ -0,0 +1,236 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+// calendarServer is an in-process stand-in of a CalDAV calendar collection
+// served under /calendars/me/news/.
+type calendarServer struct {
+	mutex     sync.Mutex
+	resources map[string]string
+	etags     map[string]string
+	version   int
+}
+
+func (s *calendarServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	username, password, ok := req.BasicAuth()
+	if !ok || username != "me" || password != "secret" {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, "/calendars/me/news/") {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	path := req.URL.Path
+	switch req.Method {
+	case "REPORT":
+		var paths []string
+		for path := range s.resources {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		rw.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(rw, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">`)
+		for _, path := range paths {
+			fmt.Fprintf(rw, `<d:response><d:href>%s</d:href><d:propstat><d:prop><d:getetag>%s</d:getetag><cal:calendar-data>%s</cal:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+				path, s.etags[path], s.resources[path])
+		}
+		fmt.Fprint(rw, `</d:multistatus>`)
+	case http.MethodPut:
+		_, exist := s.resources[path]
+		if (req.Header.Get("If-None-Match") == "*" && exist) || (req.Header.Get("If-Match") != "" && req.Header.Get("If-Match") != s.etags[path]) {
+			rw.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		data, _ := io.ReadAll(req.Body)
+		s.version++
+		s.resources[path] = string(data)
+		s.etags[path] = fmt.Sprintf(`"%d"`, s.version)
+		rw.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if _, exist := s.resources[path]; !exist {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.resources, path)
+		rw.WriteHeader(http.StatusNoContent)
+	default:
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestCalDAV(t *testing.T) {
+	ctx := context.Background()
+	today := time.Now().Format("2006-01-02")
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertError := func(t testing.TB, got, want error) {
+		t.Helper()
+		if !errors.Is(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	assertEqualString := func(t testing.TB, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	newCalDAV := func(t testing.TB) (*CalDAV, *calendarServer) {
+		t.Helper()
+		calendar := &calendarServer{resources: map[string]string{}, etags: map[string]string{}}
+		server := httptest.NewServer(calendar)
+		t.Cleanup(server.Close)
+
+		caldav := &CalDAV{
+			Client:        server.Client(),
+			CalendarUrl:   server.URL + "/calendars/me/news",
+			Username:      "me",
+			Password:      "secret",
+			MaxTodoPerDay: 2,
+		}
+		assertNoError(t, caldav.Init(ctx))
+		return caldav, calendar
+	}
+
+	t.Run("It should fold long lines and keep unknown properties", func(t *testing.T) {
+		todo := Todo{Uid: "1", Summary: strings.Repeat("é", 60) + "\nend", extra: []string{"BEGIN:VALARM", "ACTION:DISPLAY", "END:VALARM"}}
+		data := todo.Encode()
+		for _, line := range strings.Split(data, "\r\n") {
+			if len(line) > ICAL_LINE_LENGTH {
+				t.Fatalf("line %q is longer than %d octets", line, ICAL_LINE_LENGTH)
+			}
+		}
+
+		decoded, ok := DecodeTodo(data)
+		if !ok {
+			t.Fatal("VTODO not found")
+		}
+		assertEqualString(t, decoded.Summary, todo.Summary)
+		assertEqualString(t, strings.Join(decoded.extra, ","), "BEGIN:VALARM,ACTION:DISPLAY,END:VALARM")
+	})
+
+	t.Run("It should handle error on bad credentials or uninitialized client", func(t *testing.T) {
+		caldav, _ := newCalDAV(t)
+		caldav.Password = "wrong"
+		_, err := caldav.List(ctx)
+		assertError(t, err, ErrHttpRequestUnauthorized)
+
+		uninitialized := CalDAV{}
+		_, err = uninitialized.List(ctx)
+		assertError(t, err, ErrNotInitialized)
+
+		err = caldav.Init(ctx)
+		assertError(t, err, ErrHttpRequestUnauthorized)
+	})
+
+	t.Run("It should create a VTODO with a due date", func(t *testing.T) {
+		caldav, calendar := newCalDAV(t)
+		created, err := caldav.Create(ctx, sink.Item{Title: "Go, news; and more", Url: "https://go.dev", Channel: "golang", Priority: 4})
+		assertNoError(t, err)
+		assertEqualString(t, created.DueDate, today)
+
+		data := calendar.resources["/calendars/me/news/"+created.Id+".ics"]
+		for _, want := range []string{
+			"BEGIN:VTODO\r\n",
+			"SUMMARY:Go\\, news\\; and more\r\n",
+			"URL:https://go.dev\r\n",
+			"CATEGORIES:golang\r\n",
+			"DUE;VALUE=DATE:" + strings.ReplaceAll(today, "-", "") + "\r\n",
+			"STATUS:NEEDS-ACTION\r\n",
+			"PRIORITY:1\r\n",
+		} {
+			if !strings.Contains(data, want) {
+				t.Fatalf("calendar object %q should contain %q", data, want)
+			}
+		}
+
+		found, err := caldav.FindByUrl(ctx, "https://go.dev")
+		assertNoError(t, err)
+		assertEqualString(t, found.Title, "Go, news; and more")
+		if found.Priority != 4 {
+			t.Fatalf("got priority %d, want 4", found.Priority)
+		}
+
+		_, err = caldav.Create(ctx, sink.Item{Title: "again", Url: "https://go.dev"})
+		assertError(t, err, sink.ErrAlreadyExist)
+	})
+
+	t.Run("It should schedule on the next day with room", func(t *testing.T) {
+		caldav, _ := newCalDAV(t)
+		var dates []string
+		for _, url := range []string{"https://a.dev", "https://b.dev", "https://c.dev"} {
+			created, err := caldav.Create(ctx, sink.Item{Title: url, Url: url})
+			assertNoError(t, err)
+			dates = append(dates, created.DueDate)
+		}
+		assertEqualString(t, strings.Join(dates, " "), strings.Join([]string{today, today, tomorrow}, " "))
+	})
+
+	t.Run("It should create a group and complete it with its subtasks", func(t *testing.T) {
+		caldav, _ := newCalDAV(t)
+		parent, err := caldav.Create(ctx, sink.Item{Title: "Digest", Children: []sink.Item{
+			{Title: "a", Url: "https://a.dev"},
+			{Title: "b", Url: "https://b.dev"},
+		}})
+		assertNoError(t, err)
+		if len(parent.Children) != 2 || parent.Children[1].ParentId != parent.Id {
+			t.Fatalf("unexpected group %+v", parent)
+		}
+
+		assertNoError(t, caldav.Complete(ctx, parent.Id))
+		items, err := caldav.List(ctx)
+		assertNoError(t, err)
+		for _, item := range items {
+			if item.Status != sink.StatusDone {
+				t.Fatalf("item %+v should be done", item)
+			}
+		}
+		_, err = caldav.FindByUrl(ctx, "https://a.dev")
+		assertError(t, err, sink.ErrNotFound)
+	})
+
+	t.Run("It should reschedule and delete a todo", func(t *testing.T) {
+		caldav, calendar := newCalDAV(t)
+		created, err := caldav.Create(ctx, sink.Item{Title: "foo", Url: "https://foo.bar"})
+		assertNoError(t, err)
+
+		assertNoError(t, caldav.Reschedule(ctx, created.Id, tomorrow))
+		found, _ := caldav.FindByUrl(ctx, "https://foo.bar")
+		assertEqualString(t, found.DueDate, tomorrow)
+
+		assertNoError(t, caldav.Delete(ctx, created.Id))
+		if len(calendar.resources) != 0 {
+			t.Fatalf("got %d resources, want none", len(calendar.resources))
+		}
+		assertError(t, caldav.Delete(ctx, created.Id), sink.ErrNotFound)
+	})
+}
@@ -0,0 +1,235 @@
+package caldav
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/schedule"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+const (
+	ICAL_DATE_FORMAT     = "20060102"
+	ICAL_DATETIME_FORMAT = "20060102T150405Z"
+	ICAL_LINE_LENGTH     = 75
+	PRODUCT_ID           = "-//aza-discord-news-sorter//EN"
+)
+
+// Todo is the VTODO of a calendar object resource. Href and ETag are set when
+// the todo was read from the server.
+type Todo struct {
+	Uid         string
+	Summary     string
+	Url         string
+	Description string
+	Categories  string
+	Due         string
+	Status      string
+	Priority    int
+	RelatedTo   string
+	Completed   time.Time
+
+	Href string
+	ETag string
+	// extra holds the properties of the VTODO not known here, so they are
+	// written back as is.
+	extra []string
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+func unescapeText(text string) string {
+	var builder strings.Builder
+	escaped := false
+	for _, r := range text {
+		if !escaped && r == '\\' {
+			escaped = true
+			continue
+		}
+		if escaped && (r == 'n' || r == 'N') {
+			r = '\n'
+		}
+		escaped = false
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// fold splits a content line in lines of at most ICAL_LINE_LENGTH octets
+// without cutting a UTF-8 sequence.
+func fold(line string) string {
+	var builder strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > ICAL_LINE_LENGTH {
+			builder.WriteString("\r\n ")
+			length = 1
+		}
+		builder.WriteRune(r)
+		length += size
+	}
+	builder.WriteString("\r\n")
+	return builder.String()
+}
+
+func unfold(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// toIcalPriority maps the todoist like priority of the rules, 4 being the
+// most urgent, on the iCalendar scale where 1 is the highest.
+func toIcalPriority(priority int) int {
+	switch priority {
+	case 4:
+		return 1
+	case 3:
+		return 5
+	case 2:
+		return 9
+	}
+	return 0
+}
+
+func fromIcalPriority(priority int) int {
+	switch {
+	case priority >= 1 && priority <= 4:
+		return 4
+	case priority == 5:
+		return 3
+	case priority >= 6:
+		return 2
+	}
+	return 0
+}
+
+func (t Todo) Encode() string {
+	var builder strings.Builder
+	property := func(name, value string) {
+		if value != "" {
+			builder.WriteString(fold(name + ":" + value))
+		}
+	}
+
+	property("BEGIN", "VCALENDAR")
+	property("VERSION", "2.0")
+	property("PRODID", PRODUCT_ID)
+	property("BEGIN", "VTODO")
+	property("UID", t.Uid)
+	property("DTSTAMP", time.Now().UTC().Format(ICAL_DATETIME_FORMAT))
+	property("SUMMARY", textEscaper.Replace(t.Summary))
+	property("URL", t.Url)
+	property("DESCRIPTION", textEscaper.Replace(t.Description))
+	property("CATEGORIES", textEscaper.Replace(t.Categories))
+	if t.Due != "" {
+		property("DUE;VALUE=DATE", t.Due)
+	}
+	property("STATUS", t.Status)
+	if t.Priority != 0 {
+		property("PRIORITY", strconv.Itoa(t.Priority))
+	}
+	property("RELATED-TO", t.RelatedTo)
+	if !t.Completed.IsZero() {
+		property("COMPLETED", t.Completed.UTC().Format(ICAL_DATETIME_FORMAT))
+	}
+	for _, line := range t.extra {
+		builder.WriteString(fold(line))
+	}
+	property("END", "VTODO")
+	property("END", "VCALENDAR")
+	return builder.String()
+}
+
+// DecodeTodo reads the first VTODO of a calendar object. ok is false when
+// there is none.
+func DecodeTodo(data string) (todo Todo, ok bool) {
+	inTodo := false
+	for _, line := range unfold(data) {
+		nameAndParams, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		name, _, _ := strings.Cut(nameAndParams, ";")
+		name = strings.ToUpper(name)
+
+		if name == "BEGIN" && strings.EqualFold(value, "VTODO") {
+			inTodo, ok = true, true
+			continue
+		}
+		if !inTodo {
+			continue
+		}
+		if name == "END" && strings.EqualFold(value, "VTODO") {
+			break
+		}
+
+		switch name {
+		case "UID":
+			todo.Uid = value
+		case "SUMMARY":
+			todo.Summary = unescapeText(value)
+		case "URL":
+			todo.Url = value
+		case "DESCRIPTION":
+			todo.Description = unescapeText(value)
+		case "CATEGORIES":
+			todo.Categories = unescapeText(value)
+		case "DUE":
+			if len(value) >= len(ICAL_DATE_FORMAT) {
+				todo.Due = value[:len(ICAL_DATE_FORMAT)]
+			}
+		case "STATUS":
+			todo.Status = strings.ToUpper(value)
+		case "PRIORITY":
+			todo.Priority, _ = strconv.Atoi(value)
+		case "RELATED-TO":
+			todo.RelatedTo = value
+		case "COMPLETED":
+			todo.Completed, _ = time.Parse(ICAL_DATETIME_FORMAT, value)
+		case "DTSTAMP":
+		default:
+			todo.extra = append(todo.extra, line)
+		}
+	}
+	return
+}
+
+func icalDate(date string) (icalDate string, err error) {
+	parsed, err := time.Parse(schedule.DATE_FORMAT, date)
+	if err != nil {
+		return "", fmt.Errorf("invalid due date %q", date)
+	}
+	return parsed.Format(ICAL_DATE_FORMAT), nil
+}
+
+func (t Todo) item() (item sink.Item) {
+	item = sink.Item{
+		Id:       t.Uid,
+		ParentId: t.RelatedTo,
+		Title:    t.Summary,
+		Url:      t.Url,
+		Channel:  t.Categories,
+		Priority: fromIcalPriority(t.Priority),
+		Status:   sink.StatusOpen,
+	}
+	if due, err := time.Parse(ICAL_DATE_FORMAT, t.Due); err == nil {
+		item.DueDate = due.Format(schedule.DATE_FORMAT)
+	}
+	if t.Status == "COMPLETED" || t.Status == "CANCELLED" {
+		item.Status = sink.StatusDone
+	}
+	return
+}
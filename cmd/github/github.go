@@ -0,0 +1,363 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/httpapi"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/schedule"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+const (
+	BASE_URL             = "https://api.github.com"
+	LABEL                = "to-read"
+	WEEK_LABEL_PREFIX    = "week:"
+	CHANNEL_LABEL_PREFIX = "channel:"
+	MAX_ISSUES_PER_WEEK  = 10
+	MAX_WEEKS_TO_LOOK_UP = 8
+	PAGE_SIZE            = 100
+)
+
+var (
+	ErrRepositoryNotProvided   = errors.New("github repository not provided, expected owner/name")
+	ErrHttpRequestDefault      = httpapi.NewError("error on github api call", httpapi.ErrApiCall)
+	ErrHttpRequestUnauthorized = httpapi.NewError("unauthorized github access", httpapi.ErrUnauthorized)
+)
+
+var apiErrors = httpapi.Errors{
+	Default:      ErrHttpRequestDefault,
+	Unauthorized: ErrHttpRequestUnauthorized,
+	ByStatus:     map[int]error{http.StatusNotFound: sink.ErrNotFound},
+}
+
+var _ sink.TaskSink = &GitHub{}
+var _ sink.Completer = &GitHub{}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type Issue struct {
+	Number      int       `json:"number,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Body        string    `json:"body,omitempty"`
+	State       string    `json:"state,omitempty"`
+	StateReason string    `json:"state_reason,omitempty"`
+	Labels      []Label   `json:"labels,omitempty"`
+	PullRequest *struct{} `json:"pull_request,omitempty"`
+}
+
+// issueUpdate is the body of issue creations and updates, labels are given
+// by name.
+type issueUpdate struct {
+	Title       string   `json:"title,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	State       string   `json:"state,omitempty"`
+	StateReason string   `json:"state_reason,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+}
+
+// GitHub saves news as issues of Repository (owner/name) labeled with Label,
+// the week they are scheduled on and the channel they were shared in.
+// BaseUrl can point to any server with the GitHub REST api shape.
+type GitHub struct {
+	Client     *http.Client
+	BaseUrl    string
+	Token      string
+	Repository string
+	// Label marks the issues managed by the bot, LABEL when empty.
+	Label string
+	// MaxIssuesPerWeek and MaxWeeksToLookUp default to MAX_ISSUES_PER_WEEK
+	// and MAX_WEEKS_TO_LOOK_UP when left empty.
+	MaxIssuesPerWeek int
+	MaxWeeksToLookUp int
+}
+
+func (g *GitHub) label() string {
+	if g.Label == "" {
+		return LABEL
+	}
+	return g.Label
+}
+
+func (g *GitHub) capacity() schedule.Capacity {
+	capacity := schedule.Capacity{MaxPerDay: g.MaxIssuesPerWeek, MaxDaysToLookUp: g.MaxWeeksToLookUp * 7}
+	return capacity.Or(schedule.Capacity{MaxPerDay: MAX_ISSUES_PER_WEEK, MaxDaysToLookUp: MAX_WEEKS_TO_LOOK_UP * 7})
+}
+
+// WeekLabel names the ISO week of date, like week:2026-W03.
+func WeekLabel(date time.Time) string {
+	year, week := date.ISOWeek()
+	return fmt.Sprintf("%s%d-W%02d", WEEK_LABEL_PREFIX, year, week)
+}
+
+// weekStart returns the monday of the week of label.
+func weekStart(label string) (monday time.Time, ok bool) {
+	var year, week int
+	_, err := fmt.Sscanf(strings.TrimPrefix(label, WEEK_LABEL_PREFIX), "%d-W%d", &year, &week)
+	if err != nil || !strings.HasPrefix(label, WEEK_LABEL_PREFIX) {
+		return monday, false
+	}
+	// January 4th is always in the first ISO week
+	january4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.Local)
+	offset := (int(january4.Weekday()) + 6) % 7
+	return january4.AddDate(0, 0, (week-1)*7-offset), true
+}
+
+func (g *GitHub) call(ctx context.Context, method, path string, body any, answer any) (err error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	baseUrl := g.BaseUrl
+	if baseUrl == "" {
+		baseUrl = BASE_URL
+	}
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(baseUrl, "/")+"/repos/"+g.Repository+path, reader)
+	if err != nil {
+		return
+	}
+	request.Header.Set("Authorization", "Bearer "+g.Token)
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	request.Header.Set("Content-Type", "application/json")
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := apiErrors.Do(client, request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if answer == nil {
+		return
+	}
+	return json.NewDecoder(response.Body).Decode(answer)
+}
+
+// Init checks the repository can be reached with the token.
+func (g *GitHub) Init(ctx context.Context) (err error) {
+	owner, name, found := strings.Cut(g.Repository, "/")
+	if !found || owner == "" || name == "" {
+		return ErrRepositoryNotProvided
+	}
+	return g.call(ctx, http.MethodGet, "", nil, nil)
+}
+
+func (i Issue) labelNames() (names []string) {
+	for _, label := range i.Labels {
+		names = append(names, label.Name)
+	}
+	return
+}
+
+// issueUrl is the first line of the body, where the link is written.
+func (i Issue) issueUrl() string {
+	return strings.TrimSpace(strings.SplitN(i.Body, "\n", 2)[0])
+}
+
+func (i Issue) item() sink.Item {
+	item := sink.Item{
+		Id:     strconv.Itoa(i.Number),
+		Title:  i.Title,
+		Url:    i.issueUrl(),
+		Status: sink.StatusOpen,
+	}
+	if i.State == "closed" {
+		item.Status = sink.StatusDone
+	}
+	for _, label := range i.Labels {
+		if monday, ok := weekStart(label.Name); ok {
+			item.DueDate = monday.Format(schedule.DATE_FORMAT)
+		} else if channel, ok := strings.CutPrefix(label.Name, CHANNEL_LABEL_PREFIX); ok {
+			item.Channel = channel
+		}
+	}
+	return item
+}
+
+// openIssues returns the open issues managed by the bot.
+func (g *GitHub) openIssues(ctx context.Context) (issues []Issue, err error) {
+	for page := 1; ; page++ {
+		var answer []Issue
+		path := fmt.Sprintf("/issues?state=open&labels=%s&per_page=%d&page=%d", url.QueryEscape(g.label()), PAGE_SIZE, page)
+		err = g.call(ctx, http.MethodGet, path, nil, &answer)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range answer {
+			if issue.PullRequest == nil {
+				issues = append(issues, issue)
+			}
+		}
+		if len(answer) < PAGE_SIZE {
+			return
+		}
+	}
+}
+
+func findByUrl(issues []Issue, issueUrl string) *Issue {
+	for i, issue := range issues {
+		if issueUrl != "" && issue.issueUrl() == issueUrl {
+			return &issues[i]
+		}
+	}
+	return nil
+}
+
+func (g *GitHub) open(ctx context.Context, item sink.Item, week string) (created sink.Item, err error) {
+	body := item.Url
+	if item.Channel != "" {
+		body += fmt.Sprintf("\n\nShared in #%s", item.Channel)
+	}
+	labels := []string{g.label(), week}
+	if item.Channel != "" {
+		labels = append(labels, CHANNEL_LABEL_PREFIX+item.Channel)
+	}
+
+	var issue Issue
+	err = g.call(ctx, http.MethodPost, "/issues", issueUpdate{Title: item.Title, Body: body, Labels: labels}, &issue)
+	if err != nil {
+		return
+	}
+	return issue.item(), nil
+}
+
+// Create opens an issue per link, a group has no issue of its own. The
+// issues are scheduled on the first week with room for them.
+func (g *GitHub) Create(ctx context.Context, item sink.Item) (created sink.Item, err error) {
+	issues, err := g.openIssues(ctx)
+	if err != nil {
+		return
+	}
+
+	items := item.Children
+	if len(items) == 0 {
+		items = []sink.Item{item}
+	}
+	var toOpen []sink.Item
+	for _, child := range items {
+		if findByUrl(issues, child.Url) == nil {
+			if child.Channel == "" {
+				child.Channel = item.Channel
+			}
+			toOpen = append(toOpen, child)
+		}
+	}
+	if len(toOpen) == 0 {
+		return created, sink.ErrAlreadyExist
+	}
+
+	start, err := schedule.StartDate(item.DueDate)
+	if err != nil {
+		return
+	}
+	dueDate, err := g.capacity().FirstFreeDay(start, len(toOpen), func(date string) (count int, err error) {
+		day, _ := time.ParseInLocation(schedule.DATE_FORMAT, date, time.Local)
+		week := WeekLabel(day)
+		for _, issue := range issues {
+			for _, label := range issue.labelNames() {
+				if label == week {
+					count++
+				}
+			}
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	day, _ := time.ParseInLocation(schedule.DATE_FORMAT, dueDate, time.Local)
+	week := WeekLabel(day)
+
+	if len(item.Children) == 0 {
+		return g.open(ctx, toOpen[0], week)
+	}
+	created = item
+	created.Children = nil
+	for _, child := range toOpen {
+		opened, err := g.open(ctx, child, week)
+		if err != nil {
+			return created, err
+		}
+		created.Children = append(created.Children, opened)
+	}
+	return
+}
+
+func (g *GitHub) FindByUrl(ctx context.Context, issueUrl string) (item sink.Item, err error) {
+	issues, err := g.openIssues(ctx)
+	if err != nil {
+		return
+	}
+	issue := findByUrl(issues, issueUrl)
+	if issue == nil {
+		return item, sink.ErrNotFound
+	}
+	return issue.item(), nil
+}
+
+func (g *GitHub) close(ctx context.Context, id, reason string) (err error) {
+	return g.call(ctx, http.MethodPatch, "/issues/"+id, issueUpdate{State: "closed", StateReason: reason}, nil)
+}
+
+// Delete closes the issue as not planned, issues can't be deleted through
+// the REST api.
+func (g *GitHub) Delete(ctx context.Context, id string) (err error) {
+	return g.close(ctx, id, "not_planned")
+}
+
+func (g *GitHub) Complete(ctx context.Context, id string) (err error) {
+	return g.close(ctx, id, "completed")
+}
+
+// Reschedule swaps the week label of the issue.
+func (g *GitHub) Reschedule(ctx context.Context, id string, dueDate string) (err error) {
+	day, err := time.ParseInLocation(schedule.DATE_FORMAT, dueDate, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid due date %q", dueDate)
+	}
+
+	var issue Issue
+	err = g.call(ctx, http.MethodGet, "/issues/"+id, nil, &issue)
+	if err != nil {
+		return
+	}
+	labels := []string{WeekLabel(day)}
+	for _, label := range issue.labelNames() {
+		if !strings.HasPrefix(label, WEEK_LABEL_PREFIX) {
+			labels = append(labels, label)
+		}
+	}
+	return g.call(ctx, http.MethodPatch, "/issues/"+id, issueUpdate{Labels: labels}, nil)
+}
+
+// List returns the open issues managed by the bot.
+func (g *GitHub) List(ctx context.Context) (items []sink.Item, err error) {
+	issues, err := g.openIssues(ctx)
+	if err != nil {
+		return
+	}
+	for _, issue := range issues {
+		items = append(items, issue.item())
+	}
+	return
+}
@@ -0,0 +1,198 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+// fakeRepository serves the issues endpoints of the team/news repository.
+type fakeRepository struct {
+	mutex  sync.Mutex
+	issues []*Issue
+}
+
+func (f *fakeRepository) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if req.Header.Get("Authorization") != "Bearer secret" {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path, found := strings.CutPrefix(req.URL.Path, "/repos/team/news")
+	if !found {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case path == "":
+		rw.Write([]byte(`{"full_name": "team/news"}`))
+	case path == "/issues" && req.Method == http.MethodPost:
+		var update issueUpdate
+		json.NewDecoder(req.Body).Decode(&update)
+		issue := &Issue{Number: len(f.issues) + 1, Title: update.Title, Body: update.Body, State: "open"}
+		for _, label := range update.Labels {
+			issue.Labels = append(issue.Labels, Label{Name: label})
+		}
+		f.issues = append(f.issues, issue)
+		rw.WriteHeader(http.StatusCreated)
+		json.NewEncoder(rw).Encode(issue)
+	case path == "/issues":
+		issues := []Issue{{Number: 999, Title: "a pull request", State: "open", PullRequest: &struct{}{}}}
+		for _, issue := range f.issues {
+			if issue.State == req.URL.Query().Get("state") {
+				issues = append(issues, *issue)
+			}
+		}
+		json.NewEncoder(rw).Encode(issues)
+	default:
+		var number int
+		fmt.Sscanf(path, "/issues/%d", &number)
+		if number < 1 || number > len(f.issues) {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		issue := f.issues[number-1]
+		if req.Method == http.MethodPatch {
+			var update issueUpdate
+			json.NewDecoder(req.Body).Decode(&update)
+			if update.State != "" {
+				issue.State, issue.StateReason = update.State, update.StateReason
+			}
+			if update.Labels != nil {
+				issue.Labels = nil
+				for _, label := range update.Labels {
+					issue.Labels = append(issue.Labels, Label{Name: label})
+				}
+			}
+		}
+		json.NewEncoder(rw).Encode(issue)
+	}
+}
+
+func TestGitHub(t *testing.T) {
+	ctx := context.Background()
+	thisWeek := WeekLabel(time.Now())
+	nextWeek := WeekLabel(time.Now().AddDate(0, 0, 7))
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertError := func(t testing.TB, got, want error) {
+		t.Helper()
+		if !errors.Is(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	assertEqualString := func(t testing.TB, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	newGitHub := func(t testing.TB) (*GitHub, *fakeRepository) {
+		t.Helper()
+		repository := &fakeRepository{}
+		server := httptest.NewServer(repository)
+		t.Cleanup(server.Close)
+
+		github := &GitHub{Client: server.Client(), BaseUrl: server.URL, Token: "secret", Repository: "team/news", MaxIssuesPerWeek: 2}
+		assertNoError(t, github.Init(ctx))
+		return github, repository
+	}
+
+	t.Run("It should handle error on invalid repository or token", func(t *testing.T) {
+		github := &GitHub{Repository: "news"}
+		assertError(t, github.Init(ctx), ErrRepositoryNotProvided)
+
+		github, _ = newGitHub(t)
+		github.Token = "wrong"
+		assertError(t, github.Init(ctx), ErrHttpRequestUnauthorized)
+	})
+
+	t.Run("It should find the monday of a week label", func(t *testing.T) {
+		for _, date := range []string{"2026-01-01", "2026-06-17", "2027-01-03", "2020-12-31"} {
+			day, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+			monday, ok := weekStart(WeekLabel(day))
+			if !ok || monday.Weekday() != time.Monday || day.Sub(monday) < 0 || day.Sub(monday) >= 7*24*time.Hour {
+				t.Fatalf("got %s for %s", monday, date)
+			}
+		}
+	})
+
+	t.Run("It should open an issue labeled with the week and the channel", func(t *testing.T) {
+		github, repository := newGitHub(t)
+		created, err := github.Create(ctx, sink.Item{Title: "Go", Url: "https://go.dev", Channel: "golang"})
+		assertNoError(t, err)
+		assertEqualString(t, created.Id, "1")
+		assertEqualString(t, created.Channel, "golang")
+
+		issue := repository.issues[0]
+		assertEqualString(t, strings.Join(issue.labelNames(), ","), "to-read,"+thisWeek+",channel:golang")
+		assertEqualString(t, issue.Body, "https://go.dev\n\nShared in #golang")
+
+		found, err := github.FindByUrl(ctx, "https://go.dev")
+		assertNoError(t, err)
+		assertEqualString(t, found.Id, created.Id)
+
+		_, err = github.Create(ctx, sink.Item{Title: "Go", Url: "https://go.dev"})
+		assertError(t, err, sink.ErrAlreadyExist)
+	})
+
+	t.Run("It should schedule on the next week with room", func(t *testing.T) {
+		github, repository := newGitHub(t)
+		_, err := github.Create(ctx, sink.Item{Title: "a", Url: "https://a.dev"})
+		assertNoError(t, err)
+		parent, err := github.Create(ctx, sink.Item{Title: "Digest", Children: []sink.Item{
+			{Title: "b", Url: "https://b.dev"},
+			{Title: "c", Url: "https://c.dev"},
+		}})
+		assertNoError(t, err)
+		if parent.Id != "" || len(parent.Children) != 2 {
+			t.Fatalf("unexpected group %+v", parent)
+		}
+		for _, issue := range repository.issues[1:] {
+			if issue.labelNames()[1] != nextWeek {
+				t.Fatalf("issue %+v should be scheduled on %s", issue, nextWeek)
+			}
+		}
+	})
+
+	t.Run("It should close issues and reschedule them", func(t *testing.T) {
+		github, repository := newGitHub(t)
+		first, _ := github.Create(ctx, sink.Item{Title: "a", Url: "https://a.dev"})
+		second, _ := github.Create(ctx, sink.Item{Title: "b", Url: "https://b.dev"})
+		third, _ := github.Create(ctx, sink.Item{Title: "c", Url: "https://c.dev", Channel: "golang"})
+
+		assertNoError(t, github.Delete(ctx, first.Id))
+		assertNoError(t, github.Complete(ctx, second.Id))
+		assertEqualString(t, repository.issues[0].StateReason, "not_planned")
+		assertEqualString(t, repository.issues[1].StateReason, "completed")
+		assertError(t, github.Complete(ctx, "42"), sink.ErrNotFound)
+
+		assertNoError(t, github.Reschedule(ctx, third.Id, time.Now().AddDate(0, 0, 7).Format("2006-01-02")))
+		items, err := github.List(ctx)
+		assertNoError(t, err)
+		if len(items) != 1 || items[0].Channel != "golang" {
+			t.Fatalf("unexpected items %+v", items)
+		}
+		assertEqualString(t, strings.Join(repository.issues[2].labelNames(), ","), nextWeek+",to-read,channel:golang")
+	})
+}
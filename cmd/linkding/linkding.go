@@ -0,0 +1,214 @@
+package linkding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/httpapi"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+const PAGE_SIZE = 100
+
+var (
+	ErrHttpRequestDefault      = httpapi.NewError("error on linkding api call", httpapi.ErrApiCall)
+	ErrHttpRequestUnauthorized = httpapi.NewError("unauthorized linkding access", httpapi.ErrUnauthorized)
+)
+
+var apiErrors = httpapi.Errors{
+	Default:      ErrHttpRequestDefault,
+	Unauthorized: ErrHttpRequestUnauthorized,
+	ByStatus:     map[int]error{http.StatusNotFound: sink.ErrNotFound},
+}
+
+var _ sink.TaskSink = &Linkding{}
+var _ sink.Completer = &Linkding{}
+
+type Bookmark struct {
+	Id         int      `json:"id,omitempty"`
+	Url        string   `json:"url"`
+	Title      string   `json:"title,omitempty"`
+	TagNames   []string `json:"tag_names,omitempty"`
+	Unread     bool     `json:"unread"`
+	IsArchived bool     `json:"is_archived,omitempty"`
+}
+
+type bookmarkPage struct {
+	Next    *string    `json:"next"`
+	Results []Bookmark `json:"results"`
+}
+
+type bookmarkCheck struct {
+	Bookmark *Bookmark `json:"bookmark"`
+}
+
+// Linkding saves news as unread bookmarks of a Linkding compatible api at
+// BaseUrl. The news are never scheduled so Reschedule is not supported.
+type Linkding struct {
+	Client  *http.Client
+	BaseUrl string
+	Token   string
+}
+
+func (l *Linkding) do(ctx context.Context, method, path string, body any) (response *http.Response, err error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(l.BaseUrl, "/")+path, reader)
+	if err != nil {
+		return
+	}
+	request.Header.Set("Authorization", "Token "+l.Token)
+	request.Header.Set("Content-Type", "application/json")
+
+	client := l.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return apiErrors.Do(client, request)
+}
+
+func (l *Linkding) call(ctx context.Context, method, path string, body any, answer any) (err error) {
+	response, err := l.do(ctx, method, path, body)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if answer == nil {
+		return
+	}
+	return json.NewDecoder(response.Body).Decode(answer)
+}
+
+func (b Bookmark) item() sink.Item {
+	item := sink.Item{
+		Id:     strconv.Itoa(b.Id),
+		Title:  b.Title,
+		Url:    b.Url,
+		Tags:   b.TagNames,
+		Status: sink.StatusOpen,
+	}
+	if b.IsArchived {
+		item.Status = sink.StatusDone
+	}
+	return item
+}
+
+// Init checks the api can be reached with the token.
+func (l *Linkding) Init(ctx context.Context) (err error) {
+	return l.call(ctx, http.MethodGet, "/api/bookmarks/?limit=1", nil, nil)
+}
+
+func (l *Linkding) find(ctx context.Context, bookmarkUrl string) (bookmark *Bookmark, err error) {
+	var check bookmarkCheck
+	err = l.call(ctx, http.MethodGet, "/api/bookmarks/check/?url="+url.QueryEscape(bookmarkUrl), nil, &check)
+	if err != nil {
+		return
+	}
+	if check.Bookmark == nil || check.Bookmark.IsArchived {
+		return nil, sink.ErrNotFound
+	}
+	return check.Bookmark, nil
+}
+
+func (l *Linkding) create(ctx context.Context, item sink.Item) (created sink.Item, err error) {
+	_, err = l.find(ctx, item.Url)
+	if err == nil {
+		return created, sink.ErrAlreadyExist
+	}
+	if err != sink.ErrNotFound {
+		return
+	}
+
+	var bookmark Bookmark
+	err = l.call(ctx, http.MethodPost, "/api/bookmarks/", Bookmark{
+		Url:      item.Url,
+		Title:    item.Title,
+		TagNames: item.Tags,
+		Unread:   true,
+	}, &bookmark)
+	if err != nil {
+		return
+	}
+	return bookmark.item(), nil
+}
+
+// Create saves item as a bookmark. A group has no bookmark of its own, only
+// its children are saved.
+func (l *Linkding) Create(ctx context.Context, item sink.Item) (created sink.Item, err error) {
+	if len(item.Children) == 0 {
+		return l.create(ctx, item)
+	}
+
+	created = item
+	created.Children = nil
+	for _, child := range item.Children {
+		if len(child.Tags) == 0 {
+			child.Tags = item.Tags
+		}
+		bookmark, err := l.create(ctx, child)
+		if err == sink.ErrAlreadyExist {
+			continue
+		}
+		if err != nil {
+			return created, err
+		}
+		created.Children = append(created.Children, bookmark)
+	}
+	if len(created.Children) == 0 {
+		return created, sink.ErrAlreadyExist
+	}
+	return
+}
+
+func (l *Linkding) FindByUrl(ctx context.Context, url string) (item sink.Item, err error) {
+	bookmark, err := l.find(ctx, url)
+	if err != nil {
+		return
+	}
+	return bookmark.item(), nil
+}
+
+func (l *Linkding) Delete(ctx context.Context, id string) (err error) {
+	return l.call(ctx, http.MethodDelete, fmt.Sprintf("/api/bookmarks/%s/", url.PathEscape(id)), nil, nil)
+}
+
+// Complete archives the bookmark.
+func (l *Linkding) Complete(ctx context.Context, id string) (err error) {
+	return l.call(ctx, http.MethodPost, fmt.Sprintf("/api/bookmarks/%s/archive/", url.PathEscape(id)), nil, nil)
+}
+
+func (l *Linkding) Reschedule(ctx context.Context, id string, dueDate string) (err error) {
+	return sink.ErrNotSupported
+}
+
+// List returns the bookmarks not archived.
+func (l *Linkding) List(ctx context.Context) (items []sink.Item, err error) {
+	for offset := 0; ; offset += PAGE_SIZE {
+		var page bookmarkPage
+		err = l.call(ctx, http.MethodGet, fmt.Sprintf("/api/bookmarks/?limit=%d&offset=%d", PAGE_SIZE, offset), nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, bookmark := range page.Results {
+			items = append(items, bookmark.item())
+		}
+		if page.Next == nil || len(page.Results) == 0 {
+			return
+		}
+	}
+}
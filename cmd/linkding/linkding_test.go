@@ -0,0 +1,177 @@
+package linkding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+// fakeLinkding serves the bookmarks endpoints of the linkding api.
+type fakeLinkding struct {
+	mutex     sync.Mutex
+	nextId    int
+	bookmarks map[int]*Bookmark
+}
+
+func (f *fakeLinkding) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if req.Header.Get("Authorization") != "Token secret" {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/api/bookmarks/")
+	switch {
+	case path == "check/":
+		var found *Bookmark
+		for _, bookmark := range f.bookmarks {
+			if bookmark.Url == req.URL.Query().Get("url") {
+				found = bookmark
+			}
+		}
+		json.NewEncoder(rw).Encode(bookmarkCheck{Bookmark: found})
+	case path == "" && req.Method == http.MethodPost:
+		var bookmark Bookmark
+		json.NewDecoder(req.Body).Decode(&bookmark)
+		f.nextId++
+		bookmark.Id = f.nextId
+		f.bookmarks[bookmark.Id] = &bookmark
+		rw.WriteHeader(http.StatusCreated)
+		json.NewEncoder(rw).Encode(bookmark)
+	case path == "":
+		limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+		var page bookmarkPage
+		for id := 1; id <= f.nextId; id++ {
+			if bookmark, ok := f.bookmarks[id]; ok && !bookmark.IsArchived {
+				page.Results = append(page.Results, *bookmark)
+			}
+		}
+		if offset+limit < len(page.Results) {
+			next := "more"
+			page.Next = &next
+			page.Results = page.Results[offset : offset+limit]
+		} else if offset < len(page.Results) {
+			page.Results = page.Results[offset:]
+		} else {
+			page.Results = nil
+		}
+		json.NewEncoder(rw).Encode(page)
+	default:
+		var id int
+		var action string
+		fmt.Sscanf(strings.ReplaceAll(path, "/", " "), "%d %s", &id, &action)
+		bookmark, ok := f.bookmarks[id]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if action == "archive" && req.Method == http.MethodPost {
+			bookmark.IsArchived = true
+		} else if req.Method == http.MethodDelete {
+			delete(f.bookmarks, id)
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestLinkding(t *testing.T) {
+	ctx := context.Background()
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertError := func(t testing.TB, got, want error) {
+		t.Helper()
+		if !errors.Is(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	newLinkding := func(t testing.TB) (*Linkding, *fakeLinkding) {
+		t.Helper()
+		fake := &fakeLinkding{bookmarks: map[int]*Bookmark{}}
+		server := httptest.NewServer(fake)
+		t.Cleanup(server.Close)
+
+		linkding := &Linkding{Client: server.Client(), BaseUrl: server.URL + "/", Token: "secret"}
+		assertNoError(t, linkding.Init(ctx))
+		return linkding, fake
+	}
+
+	t.Run("It should handle error on invalid token", func(t *testing.T) {
+		linkding, _ := newLinkding(t)
+		linkding.Token = "wrong"
+		assertError(t, linkding.Init(ctx), ErrHttpRequestUnauthorized)
+	})
+
+	t.Run("It should save an unread bookmark with tags only once", func(t *testing.T) {
+		linkding, fake := newLinkding(t)
+		created, err := linkding.Create(ctx, sink.Item{Title: "Go", Url: "https://go.dev/?a=b&c", Tags: []string{"👍", "golang"}})
+		assertNoError(t, err)
+
+		bookmark := fake.bookmarks[1]
+		if created.Id != "1" || !bookmark.Unread || strings.Join(bookmark.TagNames, ",") != "👍,golang" {
+			t.Fatalf("unexpected bookmark %+v", bookmark)
+		}
+		found, err := linkding.FindByUrl(ctx, "https://go.dev/?a=b&c")
+		assertNoError(t, err)
+		if found.Id != created.Id {
+			t.Fatalf("got %+v, want %+v", found, created)
+		}
+
+		_, err = linkding.Create(ctx, sink.Item{Title: "Go", Url: "https://go.dev/?a=b&c"})
+		assertError(t, err, sink.ErrAlreadyExist)
+	})
+
+	t.Run("It should save every link of a group", func(t *testing.T) {
+		linkding, _ := newLinkding(t)
+		_, err := linkding.Create(ctx, sink.Item{Title: "a", Url: "https://a.dev"})
+		assertNoError(t, err)
+
+		parent, err := linkding.Create(ctx, sink.Item{Title: "Digest", Tags: []string{"news"}, Children: []sink.Item{
+			{Title: "a", Url: "https://a.dev"},
+			{Title: "b", Url: "https://b.dev"},
+		}})
+		assertNoError(t, err)
+		if parent.Id != "" || len(parent.Children) != 1 || parent.Children[0].Tags[0] != "news" {
+			t.Fatalf("unexpected group %+v", parent)
+		}
+	})
+
+	t.Run("It should archive, delete and list bookmarks", func(t *testing.T) {
+		linkding, _ := newLinkding(t)
+		for i := 0; i < PAGE_SIZE+2; i++ {
+			_, err := linkding.Create(ctx, sink.Item{Url: fmt.Sprintf("https://%d.dev", i)})
+			assertNoError(t, err)
+		}
+
+		assertNoError(t, linkding.Complete(ctx, "1"))
+		_, err := linkding.FindByUrl(ctx, "https://0.dev")
+		assertError(t, err, sink.ErrNotFound)
+		assertNoError(t, linkding.Delete(ctx, "2"))
+		assertError(t, linkding.Delete(ctx, "2"), sink.ErrNotFound)
+		assertError(t, linkding.Reschedule(ctx, "3", "2026-01-02"), sink.ErrNotSupported)
+
+		items, err := linkding.List(ctx)
+		assertNoError(t, err)
+		if len(items) != PAGE_SIZE {
+			t.Fatalf("got %d items, want %d", len(items), PAGE_SIZE)
+		}
+	})
+}
@@ -0,0 +1,266 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+const FRONT_MATTER_DELIMITER = "---"
+
+var (
+	dailyFileRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}\.md$`)
+	dailyLineRegex = regexp.MustCompile(`^(\s*)- \[([ xX])\] (.*) \^([0-9a-f]+)$`)
+	fieldRegex     = regexp.MustCompile(`\s*\[(\w+):: ([^\]\\]*)\]$`)
+	linkRegex      = regexp.MustCompile(`^\[((?:\\.|[^\\\]])*)\]\(<([^>]*)>\)$`)
+	slugRegex      = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// splitFrontMatter returns the lines of the YAML front matter of content and
+// what follows it. ok is false when content has no front matter.
+func splitFrontMatter(content string) (frontMatter []string, body string, ok bool) {
+	lines := strings.Split(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != FRONT_MATTER_DELIMITER {
+		return nil, content, false
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == FRONT_MATTER_DELIMITER {
+			return lines[1:i], strings.Join(lines[i+1:], "\n"), true
+		}
+	}
+	return nil, content, false
+}
+
+func parseValue(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, `"`) {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	return value
+}
+
+// parseNote reads a note written by renderNote. ok is false when the note
+// was not written by the vault.
+func parseNote(file, content string) (note entry, ok bool) {
+	frontMatter, body, ok := splitFrontMatter(content)
+	if !ok {
+		return note, false
+	}
+
+	note.file = file
+	note.body = body
+	for _, line := range frontMatter {
+		key, value, found := strings.Cut(line, ":")
+		if !found || strings.HasPrefix(line, " ") {
+			note.extra = append(note.extra, line)
+			continue
+		}
+		value = parseValue(value)
+		switch key {
+		case "id":
+			note.Id = value
+		case "title":
+			note.Title = value
+		case "url":
+			note.Url = value
+		case "channel":
+			note.Channel = value
+		case "scheduled":
+			note.DueDate = value
+		case "status":
+			note.Status = sink.Status(value)
+		case "priority":
+			note.Priority, _ = strconv.Atoi(value)
+		case "parent":
+			note.ParentId = value
+		default:
+			note.extra = append(note.extra, line)
+		}
+	}
+	return note, note.Id != "" && note.DueDate != ""
+}
+
+func renderNote(note entry) string {
+	var builder strings.Builder
+	field := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&builder, "%s: %s\n", key, value)
+		}
+	}
+
+	builder.WriteString(FRONT_MATTER_DELIMITER + "\n")
+	field("id", note.Id)
+	field("title", strconv.Quote(note.Title))
+	if note.Url != "" {
+		field("url", strconv.Quote(note.Url))
+	}
+	if note.Channel != "" {
+		field("channel", strconv.Quote(note.Channel))
+	}
+	field("scheduled", note.DueDate)
+	field("status", string(note.Status))
+	if note.Priority != 0 {
+		field("priority", strconv.Itoa(note.Priority))
+	}
+	field("parent", note.ParentId)
+	for _, line := range note.extra {
+		builder.WriteString(line + "\n")
+	}
+	builder.WriteString(FRONT_MATTER_DELIMITER + "\n")
+	builder.WriteString(note.body)
+	return builder.String()
+}
+
+// noteFileName names a note after its title so it reads well in the vault.
+func noteFileName(title, id string) string {
+	slug := strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		return id + ".md"
+	}
+	return slug + "-" + id + ".md"
+}
+
+var titleEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, "\n", " ", "\r", "")
+
+func unescapeTitle(title string) string {
+	var builder strings.Builder
+	escaped := false
+	for _, r := range title {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// parseDaily reads the items of a daily reading list, every other line is
+// returned as extra so it is kept on the next write.
+func parseDaily(file, content string) (items []entry, extra []string) {
+	date := strings.TrimSuffix(file, ".md")
+	if _, body, ok := splitFrontMatter(content); ok {
+		content = body
+	}
+
+	lastParent := ""
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		match := dailyLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			if strings.TrimSpace(line) != "" {
+				extra = append(extra, line)
+			}
+			continue
+		}
+
+		item := entry{file: file}
+		item.Id = match[4]
+		item.DueDate = date
+		item.Status = sink.StatusOpen
+		if match[2] != " " {
+			item.Status = sink.StatusDone
+		}
+		if match[1] != "" {
+			item.ParentId = lastParent
+		} else {
+			lastParent = item.Id
+		}
+
+		text := match[3]
+		for {
+			field := fieldRegex.FindStringSubmatchIndex(text)
+			if field == nil {
+				break
+			}
+			key, value := text[field[2]:field[3]], text[field[4]:field[5]]
+			switch key {
+			case "channel":
+				item.Channel = value
+			case "priority":
+				item.Priority, _ = strconv.Atoi(value)
+			case "parent":
+				item.ParentId = value
+			}
+			text = text[:field[0]]
+		}
+		if link := linkRegex.FindStringSubmatch(text); link != nil {
+			item.Title, item.Url = unescapeTitle(link[1]), link[2]
+		} else {
+			item.Title = unescapeTitle(text)
+		}
+		items = append(items, item)
+	}
+	return
+}
+
+func renderDailyLine(builder *strings.Builder, item entry, nested bool) {
+	if nested {
+		builder.WriteString("  ")
+	}
+	check := " "
+	if item.Status == sink.StatusDone {
+		check = "x"
+	}
+	fmt.Fprintf(builder, "- [%s] ", check)
+	if item.Url != "" {
+		fmt.Fprintf(builder, "[%s](<%s>)", titleEscaper.Replace(item.Title), item.Url)
+	} else {
+		builder.WriteString(titleEscaper.Replace(item.Title))
+	}
+	if item.Channel != "" {
+		fmt.Fprintf(builder, " [channel:: %s]", strings.NewReplacer("]", "", `\`, "").Replace(item.Channel))
+	}
+	if item.Priority != 0 {
+		fmt.Fprintf(builder, " [priority:: %d]", item.Priority)
+	}
+	if item.ParentId != "" && !nested {
+		fmt.Fprintf(builder, " [parent:: %s]", item.ParentId)
+	}
+	fmt.Fprintf(builder, " ^%s\n", item.Id)
+}
+
+// renderDaily writes the reading list of date, subtasks are nested under
+// their parent when it is scheduled the same day.
+func renderDaily(date string, items []*entry, extra []string) string {
+	var builder strings.Builder
+	builder.WriteString(FRONT_MATTER_DELIMITER + "\n")
+	fmt.Fprintf(&builder, "scheduled: %s\n", date)
+	builder.WriteString(FRONT_MATTER_DELIMITER + "\n\n")
+	for _, line := range extra {
+		builder.WriteString(line + "\n")
+	}
+	if len(extra) > 0 {
+		builder.WriteString("\n")
+	}
+
+	parents := map[string]bool{}
+	for _, item := range items {
+		if item.ParentId == "" {
+			parents[item.Id] = true
+		}
+	}
+	for _, item := range items {
+		if item.ParentId != "" && parents[item.ParentId] {
+			continue
+		}
+		renderDailyLine(&builder, *item, false)
+		if item.ParentId != "" {
+			continue
+		}
+		for _, child := range items {
+			if child.ParentId == item.Id {
+				renderDailyLine(&builder, *child, true)
+			}
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,376 @@
+package markdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/helpers"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/schedule"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+const (
+	MODE_NOTE  = "note"
+	MODE_DAILY = "daily"
+)
+
+var (
+	ErrDirNotProvided = errors.New("vault directory not provided")
+	ErrInvalidMode    = errors.New("invalid vault mode, must be note or daily")
+)
+
+var _ sink.TaskSink = &Vault{}
+var _ sink.Completer = &Vault{}
+
+// Vault saves news in a directory of Markdown files, either one note per
+// news (MODE_NOTE) or one reading list per day (MODE_DAILY).
+type Vault struct {
+	Dir             string
+	Mode            string
+	MaxTodoPerDay   int
+	MaxDaysToLookUp int
+	SlotPerLink     bool
+
+	mutex sync.Mutex
+}
+
+// entry is an item along with the file it is stored in. body and extra keep
+// what the user added to a note so it is written back as is.
+type entry struct {
+	sink.Item
+	file  string
+	body  string
+	extra []string
+}
+
+type vaultState struct {
+	entries []*entry
+	// files holds the content of the files read, by name
+	files map[string]string
+	// dailyExtra holds the lines of a reading list that are not items
+	dailyExtra map[string][]string
+}
+
+func (v *Vault) Init() (err error) {
+	if v.Dir == "" {
+		return ErrDirNotProvided
+	}
+	if v.Mode == "" {
+		v.Mode = MODE_NOTE
+	}
+	if v.Mode != MODE_NOTE && v.Mode != MODE_DAILY {
+		return ErrInvalidMode
+	}
+	return os.MkdirAll(v.Dir, 0o755)
+}
+
+func (v *Vault) load() (state vaultState, err error) {
+	state.files = map[string]string{}
+	state.dailyExtra = map[string][]string{}
+
+	files, err := os.ReadDir(v.Dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		name := file.Name()
+		if !file.Type().IsRegular() || filepath.Ext(name) != ".md" {
+			continue
+		}
+		if v.Mode == MODE_DAILY && !dailyFileRegex.MatchString(name) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(v.Dir, name))
+		if err != nil {
+			return state, err
+		}
+		content := string(data)
+
+		if v.Mode == MODE_DAILY {
+			items, extra := parseDaily(name, content)
+			if len(items) == 0 && len(extra) == 0 {
+				continue
+			}
+			for i := range items {
+				state.entries = append(state.entries, &items[i])
+			}
+			state.dailyExtra[name] = extra
+			state.files[name] = content
+			continue
+		}
+
+		note, ok := parseNote(name, content)
+		if !ok {
+			continue
+		}
+		state.entries = append(state.entries, &note)
+		state.files[name] = content
+	}
+	return
+}
+
+// save writes the files whose content changed and removes the ones left
+// without any item.
+func (v *Vault) save(state vaultState) (err error) {
+	rendered := map[string]string{}
+	if v.Mode == MODE_DAILY {
+		byDay := map[string][]*entry{}
+		for _, item := range state.entries {
+			file := item.DueDate + ".md"
+			byDay[file] = append(byDay[file], item)
+		}
+		for file, extra := range state.dailyExtra {
+			if _, ok := byDay[file]; !ok && len(extra) > 0 {
+				byDay[file] = nil
+			}
+		}
+		for file, items := range byDay {
+			rendered[file] = renderDaily(strings.TrimSuffix(file, ".md"), items, state.dailyExtra[file])
+		}
+	} else {
+		for _, note := range state.entries {
+			rendered[note.file] = renderNote(*note)
+		}
+	}
+
+	var errs []error
+	for file, content := range rendered {
+		if previous, ok := state.files[file]; ok && previous == content {
+			continue
+		}
+		errs = append(errs, helpers.WriteFileAtomic(filepath.Join(v.Dir, file), []byte(content)))
+	}
+	for file := range state.files {
+		if _, ok := rendered[file]; !ok {
+			errs = append(errs, os.Remove(filepath.Join(v.Dir, file)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s vaultState) find(id string) *entry {
+	for _, item := range s.entries {
+		if item.Id == id {
+			return item
+		}
+	}
+	return nil
+}
+
+func (s vaultState) findByUrl(url string) *entry {
+	for _, item := range s.entries {
+		if url != "" && item.Url == url && item.Status == sink.StatusOpen {
+			return item
+		}
+	}
+	return nil
+}
+
+// newId derives a short id from seed, ids stay the same when the vault is
+// synced to another machine.
+func (s vaultState) newId(seed string) string {
+	for i := 0; ; i++ {
+		hash := fnv.New32a()
+		fmt.Fprintf(hash, "%s#%d", seed, i)
+		id := fmt.Sprintf("%08x", hash.Sum32())
+		if s.find(id) == nil {
+			return id
+		}
+	}
+}
+
+// takesSlot tells whether item counts in the capacity of its day: the group
+// when a group takes a single slot, every link otherwise.
+func (v *Vault) takesSlot(item *entry) bool {
+	if v.SlotPerLink {
+		return item.Url != ""
+	}
+	return item.ParentId == ""
+}
+
+func (v *Vault) add(state *vaultState, item sink.Item, seed string) *entry {
+	item.Id = state.newId(seed)
+	item.Status = sink.StatusOpen
+	item.Children = nil
+	added := &entry{Item: item}
+	if v.Mode == MODE_NOTE {
+		added.file = noteFileName(item.Title, item.Id)
+		if item.Url != "" {
+			added.body = fmt.Sprintf("\n[%s](<%s>)\n", titleEscaper.Replace(item.Title), item.Url)
+		}
+	}
+	state.entries = append(state.entries, added)
+	return added
+}
+
+func (v *Vault) Create(ctx context.Context, item sink.Item) (created sink.Item, err error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	state, err := v.load()
+	if err != nil {
+		return
+	}
+	if state.findByUrl(item.Url) != nil {
+		return created, sink.ErrAlreadyExist
+	}
+
+	var children []sink.Item
+	for _, child := range item.Children {
+		if state.findByUrl(child.Url) == nil {
+			children = append(children, child)
+		}
+	}
+	if len(item.Children) > 0 && len(children) == 0 {
+		return created, sink.ErrAlreadyExist
+	}
+
+	slots := 1
+	if v.SlotPerLink && len(children) > 0 {
+		slots = len(children)
+	}
+	start, err := schedule.StartDate(item.DueDate)
+	if err != nil {
+		return
+	}
+	item.DueDate, err = schedule.Capacity{MaxPerDay: v.MaxTodoPerDay, MaxDaysToLookUp: v.MaxDaysToLookUp}.WithDefaults().FirstFreeDay(start, slots, func(date string) (count int, err error) {
+		for _, scheduled := range state.entries {
+			if scheduled.DueDate == date && scheduled.Status == sink.StatusOpen && v.takesSlot(scheduled) {
+				count++
+			}
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	seed := item.Url
+	if len(children) > 0 {
+		seed = item.Title
+		for _, child := range children {
+			seed += " " + child.Url
+		}
+	}
+	parent := v.add(&state, item, seed)
+	created = parent.Item
+
+	var links []string
+	for _, child := range children {
+		child.ParentId = parent.Id
+		child.DueDate = parent.DueDate
+		child.Priority = parent.Priority
+		if child.Channel == "" {
+			child.Channel = parent.Channel
+		}
+		added := v.add(&state, child, child.Url)
+		links = append(links, fmt.Sprintf("- [[%s]]", strings.TrimSuffix(added.file, ".md")))
+		created.Children = append(created.Children, added.Item)
+	}
+	if v.Mode == MODE_NOTE && len(links) > 0 {
+		parent.body = "\n" + strings.Join(links, "\n") + "\n"
+	}
+
+	err = v.save(state)
+	if err != nil {
+		return sink.Item{}, err
+	}
+	return created, nil
+}
+
+func (v *Vault) FindByUrl(ctx context.Context, url string) (item sink.Item, err error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	state, err := v.load()
+	if err != nil {
+		return
+	}
+	found := state.findByUrl(url)
+	if found == nil {
+		return item, sink.ErrNotFound
+	}
+	return found.Item, nil
+}
+
+// update applies change to the item id and its subtasks.
+func (v *Vault) update(id string, change func(item *entry)) (err error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	state, err := v.load()
+	if err != nil {
+		return
+	}
+	if state.find(id) == nil {
+		return sink.ErrNotFound
+	}
+	for _, item := range state.entries {
+		if item.Id == id || item.ParentId == id {
+			change(item)
+		}
+	}
+	return v.save(state)
+}
+
+func (v *Vault) Delete(ctx context.Context, id string) (err error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	state, err := v.load()
+	if err != nil {
+		return
+	}
+	if state.find(id) == nil {
+		return sink.ErrNotFound
+	}
+	kept := state.entries[:0]
+	for _, item := range state.entries {
+		if item.Id != id && item.ParentId != id {
+			kept = append(kept, item)
+		}
+	}
+	state.entries = kept
+	return v.save(state)
+}
+
+func (v *Vault) Complete(ctx context.Context, id string) (err error) {
+	return v.update(id, func(item *entry) {
+		item.Status = sink.StatusDone
+	})
+}
+
+func (v *Vault) Reschedule(ctx context.Context, id string, dueDate string) (err error) {
+	_, err = schedule.StartDate(dueDate)
+	if err != nil || dueDate == "" {
+		return fmt.Errorf("invalid due date %q", dueDate)
+	}
+	return v.update(id, func(item *entry) {
+		item.DueDate = dueDate
+	})
+}
+
+func (v *Vault) List(ctx context.Context) (items []sink.Item, err error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	state, err := v.load()
+	if err != nil {
+		return
+	}
+	for _, item := range state.entries {
+		items = append(items, item.Item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DueDate < items[j].DueDate
+	})
+	return
+}
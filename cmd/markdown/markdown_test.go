@@ -0,0 +1,192 @@
+package markdown
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+func TestVault(t *testing.T) {
+	ctx := context.Background()
+	today := time.Now().Format("2006-01-02")
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertError := func(t testing.TB, got, want error) {
+		t.Helper()
+		if got == nil {
+			t.Fatal("didn't get an error but wanted one")
+		}
+
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	assertEqualString := func(t testing.TB, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	newVault := func(t testing.TB, mode string) *Vault {
+		t.Helper()
+		vault := &Vault{Dir: filepath.Join(t.TempDir(), "news"), Mode: mode, MaxTodoPerDay: 2}
+		assertNoError(t, vault.Init())
+		return vault
+	}
+
+	readFile := func(t testing.TB, path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		assertNoError(t, err)
+		return string(data)
+	}
+
+	t.Run("It should reject an unknown mode", func(t *testing.T) {
+		vault := &Vault{Dir: t.TempDir(), Mode: "csv"}
+		assertError(t, vault.Init(), ErrInvalidMode)
+
+		vault = &Vault{}
+		assertError(t, vault.Init(), ErrDirNotProvided)
+	})
+
+	t.Run("It should write a note with its front matter", func(t *testing.T) {
+		vault := newVault(t, MODE_NOTE)
+		created, err := vault.Create(ctx, sink.Item{Title: `Go "1.23" released`, Url: "https://go.dev/blog", Channel: "golang", Priority: 3})
+		assertNoError(t, err)
+		assertEqualString(t, created.DueDate, today)
+
+		content := readFile(t, filepath.Join(vault.Dir, "go-1-23-released-"+created.Id+".md"))
+		for _, want := range []string{
+			"id: " + created.Id,
+			`title: "Go \"1.23\" released"`,
+			`url: "https://go.dev/blog"`,
+			`channel: "golang"`,
+			"scheduled: " + today,
+			"status: open",
+			"priority: 3",
+		} {
+			if !strings.Contains(content, want+"\n") {
+				t.Fatalf("note %q should contain %q", content, want)
+			}
+		}
+
+		found, err := vault.FindByUrl(ctx, "https://go.dev/blog")
+		assertNoError(t, err)
+		assertEqualString(t, found.Title, `Go "1.23" released`)
+		assertEqualString(t, found.Channel, "golang")
+
+		_, err = vault.Create(ctx, sink.Item{Title: "again", Url: "https://go.dev/blog"})
+		assertError(t, err, sink.ErrAlreadyExist)
+	})
+
+	t.Run("It should schedule notes on the next day with room", func(t *testing.T) {
+		vault := newVault(t, MODE_NOTE)
+		var dates []string
+		for _, url := range []string{"https://a.dev", "https://b.dev", "https://c.dev"} {
+			created, err := vault.Create(ctx, sink.Item{Title: url, Url: url})
+			assertNoError(t, err)
+			dates = append(dates, created.DueDate)
+		}
+		assertEqualString(t, strings.Join(dates, " "), strings.Join([]string{today, today, tomorrow}, " "))
+	})
+
+	t.Run("It should keep what the user added to a note", func(t *testing.T) {
+		vault := newVault(t, MODE_NOTE)
+		created, err := vault.Create(ctx, sink.Item{Title: "foo", Url: "https://foo.bar"})
+		assertNoError(t, err)
+		path := filepath.Join(vault.Dir, "foo-"+created.Id+".md")
+		content := strings.Replace(readFile(t, path), "---\n", "---\ntags: [news]\n", 1) + "\nmy thoughts\n"
+		assertNoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+		assertNoError(t, vault.Complete(ctx, created.Id))
+
+		content = readFile(t, path)
+		for _, want := range []string{"status: done", "tags: [news]", "my thoughts"} {
+			if !strings.Contains(content, want) {
+				t.Fatalf("note %q should contain %q", content, want)
+			}
+		}
+		_, err = vault.FindByUrl(ctx, "https://foo.bar")
+		assertError(t, err, sink.ErrNotFound)
+	})
+
+	t.Run("It should link the notes of a group and delete them together", func(t *testing.T) {
+		vault := newVault(t, MODE_NOTE)
+		parent, err := vault.Create(ctx, sink.Item{Title: "Digest", Children: []sink.Item{
+			{Title: "a", Url: "https://a.dev"},
+			{Title: "b", Url: "https://b.dev"},
+		}})
+		assertNoError(t, err)
+		if len(parent.Children) != 2 || parent.Children[0].ParentId != parent.Id {
+			t.Fatalf("unexpected group %+v", parent)
+		}
+		content := readFile(t, filepath.Join(vault.Dir, "digest-"+parent.Id+".md"))
+		if !strings.Contains(content, "- [[a-"+parent.Children[0].Id+"]]") {
+			t.Fatalf("group note %q should link its children", content)
+		}
+
+		assertNoError(t, vault.Delete(ctx, parent.Id))
+		files, _ := os.ReadDir(vault.Dir)
+		if len(files) != 0 {
+			t.Fatalf("got %d files, want none", len(files))
+		}
+		assertError(t, vault.Delete(ctx, parent.Id), sink.ErrNotFound)
+	})
+
+	t.Run("It should write daily reading lists", func(t *testing.T) {
+		vault := newVault(t, MODE_DAILY)
+		single, err := vault.Create(ctx, sink.Item{Title: "[Go] news", Url: "https://go.dev", Channel: "golang"})
+		assertNoError(t, err)
+		parent, err := vault.Create(ctx, sink.Item{Title: "Digest", Children: []sink.Item{
+			{Title: "a", Url: "https://a.dev"},
+			{Title: "b", Url: "https://b.dev"},
+		}})
+		assertNoError(t, err)
+		assertEqualString(t, parent.DueDate, today)
+
+		path := filepath.Join(vault.Dir, today+".md")
+		content := readFile(t, path)
+		if !strings.Contains(content, "- [ ] [\\[Go\\] news](<https://go.dev>) [channel:: golang] ^"+single.Id+"\n") {
+			t.Fatalf("unexpected reading list %q", content)
+		}
+		if !strings.Contains(content, "- [ ] Digest ^"+parent.Id+"\n  - [ ] [a](<https://a.dev>) ^"+parent.Children[0].Id+"\n") {
+			t.Fatalf("unexpected reading list %q", content)
+		}
+
+		items, err := vault.List(ctx)
+		assertNoError(t, err)
+		if len(items) != 4 || items[0].Title != "[Go] news" || items[2].ParentId != parent.Id {
+			t.Fatalf("unexpected items %+v", items)
+		}
+
+		assertNoError(t, vault.Reschedule(ctx, parent.Id, tomorrow))
+		assertNoError(t, vault.Complete(ctx, single.Id))
+		if !strings.Contains(readFile(t, path), "- [x] [\\[Go\\] news]") {
+			t.Fatal("completed news should be checked")
+		}
+		moved := readFile(t, filepath.Join(vault.Dir, tomorrow+".md"))
+		if !strings.Contains(moved, "  - [ ] [b](<https://b.dev>) ^"+parent.Children[1].Id) {
+			t.Fatalf("group should have moved with its children but got %q", moved)
+		}
+
+		assertNoError(t, vault.Delete(ctx, single.Id))
+		_, err = os.Stat(path)
+		if !os.IsNotExist(err) {
+			t.Fatal("empty reading list should be removed")
+		}
+	})
+}
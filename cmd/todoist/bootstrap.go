@@ -0,0 +1,148 @@
+package todoist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Provisioned is what the bootstrap created, empty when everything
+// already existed.
+type Provisioned struct {
+	Project  string
+	Sections []string
+	Labels   []string
+}
+
+func (p Provisioned) Empty() bool {
+	return p.Project == "" && len(p.Sections) == 0 && len(p.Labels) == 0
+}
+
+func (p Provisioned) String() string {
+	if p.Empty() {
+		return "nothing to create"
+	}
+	var created []string
+	if p.Project != "" {
+		created = append(created, fmt.Sprintf("project %q", p.Project))
+	}
+	if len(p.Sections) > 0 {
+		created = append(created, fmt.Sprintf("sections %q", p.Sections))
+	}
+	if len(p.Labels) > 0 {
+		created = append(created, fmt.Sprintf("labels %q", p.Labels))
+	}
+	return "created " + strings.Join(created, ", ")
+}
+
+func (t *Todoist) getSections(ctx context.Context, projectId string) (sections []Section, err error) {
+	return getAll[Section](ctx, t, fmt.Sprintf("%s/sections?project_id=%s", t.baseUrl, projectId))
+}
+
+func (t *Todoist) getLabels(ctx context.Context) (labels []Label, err error) {
+	return getAll[Label](ctx, t, fmt.Sprintf("%s/labels", t.baseUrl))
+}
+
+// create posts fields to the route and reads the created resource back.
+func (t *Todoist) create(ctx context.Context, route string, fields map[string]any, created any) (err error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s", t.baseUrl, route), bytes.NewBuffer(data))
+	response, err := t.do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	responseData, err := io.ReadAll(response.Body)
+	if err != nil {
+		return
+	}
+	return json.Unmarshal(responseData, created)
+}
+
+// provision creates the project, its Sections and the ones routed to, and
+// the Labels missing from todoist, the ones already there are left untouched.
+func (t *Todoist) provision(ctx context.Context, projectName string) (projectId string, provisioned Provisioned, err error) {
+	projectId, err = t.getProjectId(ctx, projectName)
+	if err == ErrProjectNotFound {
+		var project Project
+		err = t.create(ctx, "projects", map[string]any{"name": projectName}, &project)
+		if err != nil {
+			return
+		}
+		if project.Id == nil {
+			return "", provisioned, ErrProjectNotFound
+		}
+		projectId = *project.Id
+		provisioned.Project = projectName
+	}
+	if err != nil {
+		return
+	}
+
+	sections, err := t.getSections(ctx, projectId)
+	if err != nil {
+		return
+	}
+	existing := map[string]bool{}
+	for _, section := range sections {
+		if section.Name != nil {
+			existing[*section.Name] = true
+		}
+	}
+	for _, name := range t.sectionNames() {
+		if existing[name] {
+			continue
+		}
+		var section Section
+		err = t.create(ctx, "sections", map[string]any{"name": name, "project_id": projectId}, &section)
+		if err != nil {
+			return
+		}
+		existing[name] = true
+		provisioned.Sections = append(provisioned.Sections, name)
+	}
+
+	labels, err := t.getLabels(ctx)
+	if err != nil {
+		return
+	}
+	existing = map[string]bool{}
+	for _, label := range labels {
+		if label.Name != nil {
+			existing[*label.Name] = true
+		}
+	}
+	for _, name := range t.Labels {
+		if existing[name] {
+			continue
+		}
+		var label Label
+		err = t.create(ctx, "labels", map[string]any{"name": name}, &label)
+		if err != nil {
+			return
+		}
+		existing[name] = true
+		provisioned.Labels = append(provisioned.Labels, name)
+	}
+	return
+}
+
+func (t *Todoist) bootstrap(ctx context.Context, projectName string) (err error) {
+	projectId, provisioned, err := t.provision(ctx, projectName)
+	if err != nil {
+		return
+	}
+	log.Printf("todoist bootstrap of %s: %s", projectName, provisioned)
+	t.projectId = projectId
+	return
+}
@@ -0,0 +1,163 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeWorkspace serves the projects, sections and labels routes of the api
+// v1 and counts the resources created.
+type fakeWorkspace struct {
+	mutex    sync.Mutex
+	nextId   int
+	created  int
+	projects []Project
+	sections []Section
+	labels   []Label
+}
+
+func (f *fakeWorkspace) newId() *string {
+	f.nextId++
+	id := fmt.Sprint(f.nextId)
+	return &id
+}
+
+func (f *fakeWorkspace) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var fields map[string]string
+	if req.Method == http.MethodPost {
+		json.NewDecoder(req.Body).Decode(&fields)
+		f.created++
+	}
+	name := fields["name"]
+
+	switch req.Method + " " + req.URL.Path {
+	case "GET /projects":
+		json.NewEncoder(rw).Encode(page[Project]{Results: f.projects})
+	case "POST /projects":
+		project := Project{Id: f.newId(), Name: &name}
+		f.projects = append(f.projects, project)
+		json.NewEncoder(rw).Encode(project)
+	case "GET /sections":
+		var sections []Section
+		for _, section := range f.sections {
+			if *section.ProjectId == req.URL.Query().Get("project_id") {
+				sections = append(sections, section)
+			}
+		}
+		json.NewEncoder(rw).Encode(page[Section]{Results: sections})
+	case "POST /sections":
+		projectId := fields["project_id"]
+		section := Section{Id: f.newId(), ProjectId: &projectId, Name: &name}
+		f.sections = append(f.sections, section)
+		json.NewEncoder(rw).Encode(section)
+	case "GET /labels":
+		json.NewEncoder(rw).Encode(page[Label]{Results: f.labels})
+	case "POST /labels":
+		label := Label{Id: f.newId(), Name: &name}
+		f.labels = append(f.labels, label)
+		json.NewEncoder(rw).Encode(label)
+	default:
+		rw.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	ctx := context.Background()
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertEqualString := func(t testing.TB, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	newTodoist := func(t testing.TB, workspace *fakeWorkspace) *Todoist {
+		t.Helper()
+		server := httptest.NewServer(workspace)
+		t.Cleanup(server.Close)
+		return &Todoist{
+			Client:    server.Client(),
+			baseUrl:   server.URL,
+			ApiKey:    "XXX",
+			Bootstrap: true,
+			Sections:  []string{"Go", "Rust"},
+			Labels:    []string{"news"},
+		}
+	}
+
+	t.Run("It should fail on a missing project without bootstrap", func(t *testing.T) {
+		todoist := newTodoist(t, &fakeWorkspace{})
+		todoist.Bootstrap = false
+
+		err := todoist.InitContext(ctx, "News")
+		if err != ErrProjectNotFound {
+			t.Fatalf("got %v, want %v", err, ErrProjectNotFound)
+		}
+	})
+
+	t.Run("It should create the project, its sections and the labels", func(t *testing.T) {
+		workspace := &fakeWorkspace{}
+		todoist := newTodoist(t, workspace)
+		todoist.apiKey = todoist.ApiKey
+
+		projectId, provisioned, err := todoist.provision(ctx, "News")
+
+		assertNoError(t, err)
+		assertEqualString(t, projectId, *workspace.projects[0].Id)
+		assertEqualString(t, provisioned.String(), `created project "News", sections ["Go" "Rust"], labels ["news"]`)
+		for _, section := range workspace.sections {
+			assertEqualString(t, *section.ProjectId, projectId)
+		}
+	})
+
+	t.Run("It should change nothing when run twice", func(t *testing.T) {
+		workspace := &fakeWorkspace{}
+		todoist := newTodoist(t, workspace)
+		assertNoError(t, todoist.InitContext(ctx, "News"))
+		created := workspace.created
+
+		assertNoError(t, todoist.InitContext(ctx, "News"))
+		_, provisioned, err := todoist.provision(ctx, "News")
+
+		assertNoError(t, err)
+		if !provisioned.Empty() || workspace.created != created {
+			t.Fatalf("nothing should be created again, got %s and %d creations", provisioned, workspace.created-created)
+		}
+		assertEqualString(t, todoist.projectId, *workspace.projects[0].Id)
+	})
+
+	t.Run("It should only create what is missing", func(t *testing.T) {
+		projectId, name, section, label := "7", "News", "Go", "news"
+		workspace := &fakeWorkspace{
+			nextId:   10,
+			projects: []Project{{Id: &projectId, Name: &name}},
+			sections: []Section{{Id: &projectId, ProjectId: &projectId, Name: &section}},
+			labels:   []Label{{Id: &projectId, Name: &label}},
+		}
+		todoist := newTodoist(t, workspace)
+		todoist.apiKey = todoist.ApiKey
+
+		_, provisioned, err := todoist.provision(ctx, name)
+
+		assertNoError(t, err)
+		assertEqualString(t, provisioned.String(), `created sections ["Rust"]`)
+		if len(workspace.sections) != 2 || *workspace.sections[1].Name != "Rust" {
+			t.Fatalf("unexpected sections %+v", workspace.sections)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package todoist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/schedule"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+// COMPLETED_RANGE is the longest period the completed todos are listed for
+// at once, todoist refuses more than 3 months.
+const COMPLETED_RANGE = 30 * 24 * time.Hour
+
+// getCompletedTodos lists the todos of the project completed between since
+// and until.
+func (t *Todoist) getCompletedTodos(ctx context.Context, since, until time.Time) (todos []Task, err error) {
+	for start := since; start.Before(until); start = start.Add(COMPLETED_RANGE) {
+		end := start.Add(COMPLETED_RANGE)
+		if end.After(until) {
+			end = until
+		}
+		query := url.Values{
+			"project_id": {t.projectId},
+			"since":      {start.UTC().Format(time.RFC3339)},
+			"until":      {end.UTC().Format(time.RFC3339)},
+		}
+		completed, err := getAll[Task](ctx, t, fmt.Sprintf("%s/tasks/completed/by_completion_date?%s", t.baseUrl, query.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, completed...)
+	}
+	return
+}
+
+// alreadyRead returns an AlreadyReadError for each of urls completed within
+// CompletedLookback, by url. The rest/v2 api can't list the completed todos.
+func (t *Todoist) alreadyRead(ctx context.Context, urls []string) (read map[string]error, err error) {
+	read = map[string]error{}
+	if t.CompletedLookback <= 0 || t.ApiVersion == API_VERSION_REST_V2 || len(urls) == 0 {
+		return
+	}
+
+	completed, err := t.completedTodos(ctx)
+	if err != nil {
+		return
+	}
+	for _, url := range urls {
+		match := hasUrl(url)
+		for _, todo := range completed {
+			if match(todo) {
+				read[url] = &sink.AlreadyReadError{Url: url, Date: completionDate(todo)}
+				break
+			}
+		}
+	}
+	return
+}
+
+// completedTodos returns the todos completed within CompletedLookback. They
+// are listed once, then only the ones completed since the last listing.
+func (t *Todoist) completedTodos(ctx context.Context) (completed []Task, err error) {
+	t.completedMutex.Lock()
+	defer t.completedMutex.Unlock()
+
+	now := time.Now()
+	oldest := now.Add(-t.CompletedLookback)
+	since := t.completedUntil
+	if since.Before(oldest) {
+		since = oldest
+	}
+	latest, err := t.getCompletedTodos(ctx, since, now)
+	if err != nil {
+		return
+	}
+	t.completedUntil = now
+
+	kept := make([]Task, 0, len(t.completed)+len(latest))
+	for _, todo := range append(t.completed, latest...) {
+		if todo.CompletedAt != nil {
+			completedAt, err := time.Parse(time.RFC3339, *todo.CompletedAt)
+			if err == nil && completedAt.Before(oldest) {
+				continue
+			}
+		}
+		kept = append(kept, todo)
+	}
+	t.completed = kept
+	return kept, nil
+}
+
+// completionDate is the local day todo was completed on.
+func completionDate(todo Task) string {
+	if todo.CompletedAt == nil {
+		return "an unknown date"
+	}
+	completedAt, err := time.Parse(time.RFC3339, *todo.CompletedAt)
+	if err != nil {
+		return *todo.CompletedAt
+	}
+	return completedAt.Local().Format(schedule.DATE_FORMAT)
+}
+
+// skipAlreadyRead drops the items already read, readErr joins their
+// AlreadyReadError.
+func (t *Todoist) skipAlreadyRead(ctx context.Context, items []TodoItem) (remaining []TodoItem, readErr error, err error) {
+	urls := make([]string, 0, len(items))
+	for _, item := range items {
+		urls = append(urls, item.Description)
+	}
+	read, err := t.alreadyRead(ctx, urls)
+	if err != nil {
+		return
+	}
+
+	var readErrs []error
+	for _, item := range items {
+		if read[item.Description] != nil {
+			readErrs = append(readErrs, read[item.Description])
+			continue
+		}
+		remaining = append(remaining, item)
+	}
+	return remaining, errors.Join(readErrs...), nil
+}
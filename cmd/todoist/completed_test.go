@@ -0,0 +1,143 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+func TestCompleted(t *testing.T) {
+	ctx := context.Background()
+	completedAt := time.Now().AddDate(0, 0, -40).UTC()
+	completedDay := completedAt.Local().Format("2006-01-02")
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertAlreadyRead := func(t testing.TB, err error, url string) {
+		t.Helper()
+		var readErr *sink.AlreadyReadError
+		if !errors.As(err, &readErr) {
+			t.Fatalf("got %v, want an AlreadyReadError", err)
+		}
+		if readErr.Url != url || readErr.Date != completedDay {
+			t.Fatalf("got %+v, want %s read on %s", readErr, url, completedDay)
+		}
+	}
+
+	newTodoist := func(t testing.TB, lookback time.Duration) (*Todoist, *int) {
+		t.Helper()
+		var mutex sync.Mutex
+		windows := 0
+		id, description, date := "1", "https://foo.dev/?utm_source=rss", completedAt.Format(time.RFC3339)
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch {
+			case req.URL.Path == "/tasks/completed/by_completion_date":
+				windows++
+				since, _ := time.Parse(time.RFC3339, req.URL.Query().Get("since"))
+				until, _ := time.Parse(time.RFC3339, req.URL.Query().Get("until"))
+				items := []Task{}
+				if !completedAt.Before(since) && completedAt.Before(until) {
+					items = append(items, Task{Id: &id, Description: &description, CompletedAt: &date})
+				}
+				json.NewEncoder(rw).Encode(map[string]any{"items": items, "next_cursor": nil})
+			case req.Method == http.MethodGet:
+				rw.Write([]byte(`{"results": [], "next_cursor": null}`))
+			default:
+				rw.Write([]byte(`{"id": "2"}`))
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		todoist := &Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: "12345", CompletedLookback: lookback}
+		return todoist, &windows
+	}
+
+	t.Run("It should refuse a todo already read within the lookback", func(t *testing.T) {
+		todoist, windows := newTodoist(t, 90*24*time.Hour)
+
+		_, err := todoist.CreateTodoWithOptionsContext(ctx, "foo", "https://foo.dev", TodoOptions{})
+		assertAlreadyRead(t, err, "https://foo.dev")
+		if *windows != 3 {
+			t.Fatalf("got %d listings, want 3", *windows)
+		}
+	})
+
+	t.Run("It should only list the todos completed since the last create", func(t *testing.T) {
+		todoist, windows := newTodoist(t, 90*24*time.Hour)
+
+		_, err := todoist.CreateTodoWithOptionsContext(ctx, "bar", "https://bar.dev", TodoOptions{})
+		assertNoError(t, err)
+		_, err = todoist.CreateTodoWithOptionsContext(ctx, "foo", "https://foo.dev", TodoOptions{})
+		assertAlreadyRead(t, err, "https://foo.dev")
+		if *windows != 4 {
+			t.Fatalf("got %d listings, want 3 then 1", *windows)
+		}
+	})
+
+	t.Run("It should create a todo read before the lookback", func(t *testing.T) {
+		todoist, _ := newTodoist(t, 30*24*time.Hour)
+
+		_, err := todoist.CreateTodoWithOptionsContext(ctx, "foo", "https://foo.dev", TodoOptions{})
+		assertNoError(t, err)
+	})
+
+	t.Run("It should not look completed todos up when disabled", func(t *testing.T) {
+		todoist, windows := newTodoist(t, 0)
+
+		_, err := todoist.CreateTodoWithOptionsContext(ctx, "foo", "https://foo.dev", TodoOptions{})
+		assertNoError(t, err)
+		if *windows != 0 {
+			t.Fatalf("got %d listings, want none", *windows)
+		}
+	})
+
+	t.Run("It should not look completed todos up on the rest/v2 api", func(t *testing.T) {
+		todoist, windows := newTodoist(t, 90*24*time.Hour)
+		todoist.ApiVersion = API_VERSION_REST_V2
+
+		_, err := todoist.CreateTodoWithOptionsContext(ctx, "foo", "https://foo.dev", TodoOptions{})
+		assertNoError(t, err)
+		if *windows != 0 {
+			t.Fatalf("got %d listings, want none", *windows)
+		}
+	})
+
+	t.Run("It should group the links not read yet", func(t *testing.T) {
+		todoist, _ := newTodoist(t, 90*24*time.Hour)
+		items := []TodoItem{{Title: "foo", Description: "https://foo.dev"}, {Title: "bar", Description: "https://bar.dev"}}
+
+		parent, children, err := todoist.CreateTodoGroupContext(ctx, "digest", items, TodoOptions{})
+		assertAlreadyRead(t, err, "https://foo.dev")
+		if parent.Id == nil || len(children) != 1 {
+			t.Fatalf("got %+v %+v, want a group of bar only", parent, children)
+		}
+
+		_, _, err = todoist.CreateTodoGroupContext(ctx, "digest", items[:1], TodoOptions{})
+		assertAlreadyRead(t, err, "https://foo.dev")
+	})
+
+	t.Run("It should tell the links already read among the todos created", func(t *testing.T) {
+		todoist, _ := newTodoist(t, 90*24*time.Hour)
+		items := []TodoItem{{Title: "foo", Description: "https://foo.dev"}, {Title: "bar", Description: "https://bar.dev"}}
+
+		created, err := todoist.CreateTodosContext(ctx, items, TodoOptions{})
+		assertAlreadyRead(t, err, "https://foo.dev")
+		if len(created) != 1 {
+			t.Fatalf("got %+v, want bar only", created)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package todoist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Migration is what MigrateLabels changed.
+type Migration struct {
+	Todos  int
+	Labels []string
+}
+
+func (m Migration) String() string {
+	if m.Todos == 0 && len(m.Labels) == 0 {
+		return "nothing to migrate"
+	}
+	return fmt.Sprintf("stripped the legacy labels of %d todos, deleted labels %q", m.Todos, m.Labels)
+}
+
+// titleLabel is the label the former versions deduplicated a todo on.
+func titleLabel(title string) string {
+	return strings.ReplaceAll(strings.Trim(title, " "), " ", "-")
+}
+
+// withoutLegacyLabels returns the labels of todo but its date ones and the
+// slug of its own title.
+func withoutLegacyLabels(todo Task) (labels []string) {
+	labels = []string{}
+	for _, label := range todo.Labels {
+		if isDateLabel(label) || (todo.Content != nil && label == titleLabel(*todo.Content)) {
+			continue
+		}
+		labels = append(labels, label)
+	}
+	return
+}
+
+// MigrateLabels strips the date and title labels the former versions put on
+// the open todos of the project, then deletes the labels it stripped that no
+// open todo uses anymore. The completed todos are skipped. It can be run
+// again safely.
+func (t *Todoist) MigrateLabels(ctx context.Context) (migration Migration, err error) {
+	if t.apiKey == "" {
+		return migration, ErrNotInitialized
+	}
+
+	todos, err := t.projectTodos(ctx)
+	if err != nil {
+		return
+	}
+	var commands []command
+	stripped := map[string]bool{}
+	for _, todo := range todos {
+		labels := withoutLegacyLabels(todo)
+		if todo.Id == nil || len(labels) == len(todo.Labels) {
+			continue
+		}
+
+		migration.Todos++
+		for _, label := range todo.Labels {
+			if !slices.Contains(labels, label) {
+				stripped[label] = true
+			}
+		}
+		fields := map[string]any{"labels": labels}
+		if t.SyncMode {
+			commands = append(commands, updateCommand(*todo.Id, fields))
+			continue
+		}
+		err = t.updateTodo(ctx, *todo.Id, fields)
+		if err != nil {
+			return
+		}
+		todo.Labels = labels
+		t.remember(todo)
+	}
+	if len(commands) > 0 {
+		_, err = t.sync(ctx, commands)
+		if err != nil {
+			return
+		}
+	}
+
+	migration.Labels, err = t.deleteUnusedLabels(ctx, stripped)
+	return
+}
+
+// deleteUnusedLabels deletes the stripped labels left on no open todo of any
+// project.
+func (t *Todoist) deleteUnusedLabels(ctx context.Context, stripped map[string]bool) (deleted []string, err error) {
+	if len(stripped) == 0 {
+		return
+	}
+
+	todos, err := t.getTodos(ctx, fmt.Sprintf("%s/tasks", t.baseUrl))
+	if err != nil {
+		return
+	}
+	used := map[string]bool{}
+	for _, todo := range todos {
+		for _, label := range todo.Labels {
+			used[label] = true
+		}
+	}
+
+	labels, err := t.getLabels(ctx)
+	if err != nil {
+		return
+	}
+	for _, label := range labels {
+		if label.Id == nil || label.Name == nil || !stripped[*label.Name] || used[*label.Name] {
+			continue
+		}
+
+		url := fmt.Sprintf("%s/labels/%s", t.baseUrl, *label.Id)
+		request, _ := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+		response, err := t.do(request)
+		if err != nil {
+			return deleted, err
+		}
+		response.Body.Close()
+		deleted = append(deleted, *label.Name)
+	}
+	return
+}
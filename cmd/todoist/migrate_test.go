@@ -0,0 +1,94 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMigrateLabels(t *testing.T) {
+	ctx := context.Background()
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	newTask := func(id, projectId, content string, labels ...string) Task {
+		return Task{Id: &id, ProjectId: &projectId, Content: &content, Labels: labels}
+	}
+
+	newLabel := func(id, name string) Label {
+		return Label{Id: &id, Name: &name}
+	}
+
+	t.Run("It should strip the legacy labels and delete the stripped ones left unused", func(t *testing.T) {
+		tasks := []Task{
+			newTask("1", "12345", "Go 1.24 is released", "Go-1.24-is-released", "2026-01-02", "news"),
+			newTask("2", "12345", "already clean", "news", "to-read"),
+			newTask("3", "other", "elsewhere", "2026-01-03"),
+			newTask("4", "12345", "Rust 2.0, edited", "Rust-2.0-is-out", "news"),
+		}
+		labels := []Label{
+			newLabel("10", "2026-01-02"), newLabel("11", "2026-01-03"), newLabel("12", "news"),
+			newLabel("13", "Go-1.24-is-released"), newLabel("14", "Rust-2.0-is-out"), newLabel("15", "to-read"),
+			newLabel("16", "2025-12-31"),
+		}
+		updates := map[string][]string{}
+		var deleted []string
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method == http.MethodPost:
+				var fields struct {
+					Labels []string `json:"labels"`
+				}
+				data, _ := io.ReadAll(req.Body)
+				json.Unmarshal(data, &fields)
+				id := strings.TrimPrefix(req.URL.Path, "/tasks/")
+				updates[id] = fields.Labels
+				for i := range tasks {
+					if *tasks[i].Id == id {
+						tasks[i].Labels = fields.Labels
+					}
+				}
+				rw.Write(data)
+			case req.Method == http.MethodDelete:
+				deleted = append(deleted, strings.TrimPrefix(req.URL.Path, "/labels/"))
+			case req.URL.Path == "/labels":
+				json.NewEncoder(rw).Encode(labels)
+			case req.URL.Query().Get("project_id") == "12345":
+				json.NewEncoder(rw).Encode([]Task{tasks[0], tasks[1], tasks[3]})
+			default:
+				json.NewEncoder(rw).Encode(tasks)
+			}
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: "12345"}
+		migration, err := todoist.MigrateLabels(ctx)
+
+		assertNoError(t, err)
+		if len(updates) != 1 || !slices.Equal(updates["1"], []string{"news"}) {
+			t.Fatalf("only the date label and the title slug of todo 1 should be stripped, got %v", updates)
+		}
+		if !slices.Equal(deleted, []string{"10", "13"}) {
+			t.Fatalf("only the labels stripped and no longer used should be deleted, got %v", deleted)
+		}
+		if migration.Todos != 1 || !slices.Equal(migration.Labels, []string{"2026-01-02", "Go-1.24-is-released"}) {
+			t.Fatalf("unexpected migration %+v", migration)
+		}
+	})
+
+	t.Run("It should tell when there is nothing to migrate", func(t *testing.T) {
+		if got := (Migration{}).String(); got != "nothing to migrate" {
+			t.Fatalf("got %q", got)
+		}
+	})
+}
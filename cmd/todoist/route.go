@@ -0,0 +1,108 @@
+package todoist
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/reactions"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+var ErrSectionNotFound = sink.NewPermanentError("todoist section not found")
+
+// Route sends the todos matching every field set to the section named
+// Section. Channel is the discord channel name, Emoji the reaction whatever
+// its skin tone and Domain the site of the news, its subdomains included.
+type Route struct {
+	Channel string
+	Emoji   string
+	Domain  string
+	Section string
+}
+
+// Matches tells if item is sent to the section of r.
+func (r Route) Matches(item sink.Item) bool {
+	if r.Channel == "" && r.Emoji == "" && r.Domain == "" {
+		return false
+	}
+	if r.Channel != "" && !strings.EqualFold(strings.TrimPrefix(r.Channel, "#"), item.Channel) {
+		return false
+	}
+	if r.Emoji != "" && reactions.StripSkinTone(r.Emoji) != reactions.StripSkinTone(item.Emoji) {
+		return false
+	}
+	return r.Domain == "" || hasDomain(itemUrl(item), r.Domain)
+}
+
+// itemUrl is the url of the news, the one of the first link for a group.
+func itemUrl(item sink.Item) string {
+	if item.Url == "" && len(item.Children) > 0 {
+		return item.Children[0].Url
+	}
+	return item.Url
+}
+
+func hasDomain(rawUrl, domain string) bool {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(strings.TrimPrefix(parsed.Hostname(), "www."))
+	domain = strings.ToLower(strings.TrimPrefix(domain, "www."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// route returns the id of the section of the first route matching item,
+// empty for the project root.
+func (t *Todoist) route(item sink.Item) (sectionId string) {
+	for _, route := range t.Routes {
+		if route.Matches(item) {
+			return t.sectionIds[route.Section]
+		}
+	}
+	return ""
+}
+
+// sectionNames returns the Sections and the ones routed to, once each.
+func (t *Todoist) sectionNames() (names []string) {
+	seen := map[string]bool{}
+	for _, name := range t.Sections {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, route := range t.Routes {
+		if !seen[route.Section] {
+			seen[route.Section] = true
+			names = append(names, route.Section)
+		}
+	}
+	return
+}
+
+// resolveSections looks up the id of every section routed to.
+func (t *Todoist) resolveSections(ctx context.Context) (err error) {
+	if len(t.Routes) == 0 {
+		return
+	}
+	sections, err := t.getSections(ctx, t.projectId)
+	if err != nil {
+		return
+	}
+
+	t.sectionIds = map[string]string{}
+	for _, section := range sections {
+		if section.Id != nil && section.Name != nil {
+			t.sectionIds[*section.Name] = *section.Id
+		}
+	}
+	for _, route := range t.Routes {
+		if _, ok := t.sectionIds[route.Section]; !ok {
+			return fmt.Errorf("%w: %q in %s", ErrSectionNotFound, route.Section, t.ProjectName)
+		}
+	}
+	return
+}
@@ -0,0 +1,124 @@
+package todoist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+func TestRoute(t *testing.T) {
+	ctx := context.Background()
+	routes := []Route{
+		{Channel: "#golang", Section: "Go"},
+		{Emoji: "📚", Section: "Books"},
+		{Domain: "github.com", Section: "Repositories"},
+		{Channel: "rust", Domain: "blog.rust-lang.org", Section: "Rust releases"},
+		{Emoji: "👍🏻", Section: "Liked"},
+	}
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertEqualString := func(t testing.TB, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	// newTodoist serves the sections from a workspace and the tasks from a
+	// project, with the routed sections already created.
+	newTodoist := func(t testing.TB) (*Todoist, *fakeProject) {
+		t.Helper()
+		projectId, name := "1", "News"
+		workspace := &fakeWorkspace{nextId: 1, projects: []Project{{Id: &projectId, Name: &name}}}
+		project := &fakeProject{}
+		mux := http.NewServeMux()
+		mux.Handle("/tasks", project)
+		mux.Handle("/tasks/", project)
+		mux.Handle("/", workspace)
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		bootstrap := &Todoist{Client: server.Client(), baseUrl: server.URL, ApiKey: "XXX", Bootstrap: true, Routes: routes}
+		assertNoError(t, bootstrap.Init(name))
+
+		todoist := &Todoist{Client: server.Client(), baseUrl: server.URL, ApiKey: "XXX", Routes: routes, SnapshotInterval: time.Minute}
+		assertNoError(t, todoist.Init(name))
+		return todoist, project
+	}
+
+	t.Run("It should match the channel, the emoji or the domain of a news", func(t *testing.T) {
+		cases := []struct {
+			item    sink.Item
+			section string
+		}{
+			{sink.Item{Channel: "GoLang", Url: "https://go.dev"}, "Go"},
+			{sink.Item{Channel: "golang", Emoji: "📚", Url: "https://github.com/golang/go"}, "Go"},
+			{sink.Item{Channel: "general", Emoji: "📚"}, "Books"},
+			{sink.Item{Url: "https://www.github.com/golang/go"}, "Repositories"},
+			{sink.Item{Url: "https://gist.github.com/someone"}, "Repositories"},
+			{sink.Item{Url: "https://notgithub.com"}, ""},
+			{sink.Item{Children: []sink.Item{{Url: "https://github.com/a"}, {Url: "https://go.dev"}}}, "Repositories"},
+			{sink.Item{Channel: "rust", Url: "https://blog.rust-lang.org/2026/10/01"}, "Rust releases"},
+			{sink.Item{Channel: "general", Url: "https://blog.rust-lang.org/2026/10/01"}, ""},
+			{sink.Item{Emoji: "👍"}, "Liked"},
+			{sink.Item{Emoji: "👍🏽"}, "Liked"},
+		}
+		todoist := &Todoist{Routes: routes, sectionIds: map[string]string{}}
+		for _, route := range routes {
+			todoist.sectionIds[route.Section] = route.Section
+		}
+		for _, c := range cases {
+			if section := todoist.route(c.item); section != c.section {
+				t.Fatalf("got section %q for %+v, want %q", section, c.item, c.section)
+			}
+		}
+	})
+
+	t.Run("It should fail on init when a routed section is missing", func(t *testing.T) {
+		projectId, name := "1", "News"
+		server := httptest.NewServer(&fakeWorkspace{projects: []Project{{Id: &projectId, Name: &name}}})
+		defer server.Close()
+
+		todoist := &Todoist{Client: server.Client(), baseUrl: server.URL, ApiKey: "XXX", Routes: routes}
+		err := todoist.Init(name)
+		if !errors.Is(err, ErrSectionNotFound) {
+			t.Fatalf("got %v, want %v", err, ErrSectionNotFound)
+		}
+	})
+
+	t.Run("It should create the todo in the section routed to", func(t *testing.T) {
+		todoist, project := newTodoist(t)
+
+		_, err := todoist.Create(ctx, sink.Item{Title: "Go 2", Url: "https://go.dev/blog", Channel: "golang"})
+		assertNoError(t, err)
+		_, err = todoist.Create(ctx, sink.Item{Title: "Unrouted", Url: "https://example.com", Channel: "general"})
+		assertNoError(t, err)
+		_, err = todoist.Create(ctx, sink.Item{Title: "Digest", Channel: "general", Children: []sink.Item{
+			{Title: "a", Url: "https://github.com/a"},
+			{Title: "b", Url: "https://github.com/b"},
+		}})
+		assertNoError(t, err)
+
+		if len(project.tasks) != 5 {
+			t.Fatalf("got %d tasks, want 5", len(project.tasks))
+		}
+		assertEqualString(t, *project.tasks[0].SectionId, todoist.sectionIds["Go"])
+		if project.tasks[1].SectionId != nil {
+			t.Fatalf("unrouted todo should stay at the project root, got section %q", *project.tasks[1].SectionId)
+		}
+		for _, task := range project.tasks[2:] {
+			assertEqualString(t, *task.SectionId, todoist.sectionIds["Repositories"])
+		}
+	})
+}
@@ -0,0 +1,229 @@
+package todoist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/schedule"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+var (
+	_ sink.TaskSink  = &Todoist{}
+	_ sink.Completer = &Todoist{}
+)
+
+func taskToItem(task Task) (item sink.Item) {
+	if task.Id != nil {
+		item.Id = *task.Id
+	}
+	if task.ParentId != nil {
+		item.ParentId = *task.ParentId
+	}
+	if task.Content != nil {
+		item.Title = *task.Content
+	}
+	if task.Description != nil {
+		item.Url = *task.Description
+	}
+	if task.Due != nil && task.Due.Date != nil {
+		item.DueDate = *task.Due.Date
+	} else if task.DueDate != nil {
+		item.DueDate = *task.DueDate
+	}
+	item.Priority = task.Priority
+	item.Status = sink.StatusOpen
+	if task.IsCompleted || task.Checked {
+		item.Status = sink.StatusDone
+	}
+	return
+}
+
+func isDateLabel(label string) bool {
+	_, err := time.Parse(schedule.DATE_FORMAT, label)
+	return err == nil
+}
+
+func (t *Todoist) itemOptions(item sink.Item) (options TodoOptions, err error) {
+	options.Priority = item.Priority
+	options.SectionId = t.route(item)
+	if item.DueDate != "" {
+		options.StartDate, err = schedule.StartDate(item.DueDate)
+	}
+	return
+}
+
+func (t *Todoist) Create(ctx context.Context, item sink.Item) (created sink.Item, err error) {
+	options, err := t.itemOptions(item)
+	if err != nil {
+		return
+	}
+
+	if len(item.Children) == 0 {
+		todo, err := t.CreateTodoWithOptionsContext(ctx, item.Title, item.Url, options)
+		if err != nil {
+			return created, err
+		}
+		return taskToItem(todo), nil
+	}
+
+	todoItems := make([]TodoItem, 0, len(item.Children))
+	for _, child := range item.Children {
+		todoItems = append(todoItems, TodoItem{Title: child.Title, Description: child.Url})
+	}
+	parent, children, err := t.CreateTodoGroupContext(ctx, item.Title, todoItems, options)
+	if parent.Id != nil {
+		created = taskToItem(parent)
+	}
+	for _, child := range children {
+		created.Children = append(created.Children, taskToItem(child))
+	}
+	return
+}
+
+func (t *Todoist) FindByUrl(ctx context.Context, url string) (item sink.Item, err error) {
+	todo, err := t.FindTodoContext(ctx, url)
+	if err != nil {
+		return
+	}
+	return taskToItem(todo), nil
+}
+
+func (t *Todoist) Delete(ctx context.Context, id string) error {
+	return t.DeleteTodoContext(ctx, id)
+}
+
+func (t *Todoist) Complete(ctx context.Context, id string) error {
+	return t.CloseTodoContext(ctx, id)
+}
+
+func (t *Todoist) List(ctx context.Context) (items []sink.Item, err error) {
+	if t.apiKey == "" {
+		return nil, ErrNotInitialized
+	}
+
+	todos, err := t.projectTodos(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, todo := range todos {
+		items = append(items, taskToItem(todo))
+	}
+	return
+}
+
+func (t *Todoist) getTodo(ctx context.Context, id string) (todo Task, err error) {
+	if t.SyncMode {
+		return t.localTodo(id)
+	}
+
+	url := fmt.Sprintf("%s/tasks/%s", t.baseUrl, id)
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	response, err := t.do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	responseData, err := io.ReadAll(response.Body)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(responseData, &todo)
+	return
+}
+
+// updateCommand turns the fields of a rest update into an item_update
+// command, the sync api takes the due date as an object.
+func updateCommand(id string, fields map[string]any) command {
+	args := map[string]any{"id": id}
+	for name, value := range fields {
+		if name == "due_date" {
+			args["due"] = map[string]any{"date": value}
+			continue
+		}
+		args[name] = value
+	}
+	return newCommand("item_update", args)
+}
+
+func (t *Todoist) updateTodo(ctx context.Context, id string, fields map[string]any) (err error) {
+	if t.SyncMode {
+		_, err = t.sync(ctx, []command{updateCommand(id, fields)})
+		return
+	}
+
+	url := fmt.Sprintf("%s/tasks/%s", t.baseUrl, id)
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(data))
+	response, err := t.do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	return
+}
+
+// Reschedule moves the todo to dueDate, the legacy date labels are dropped
+// along the way.
+func (t *Todoist) Reschedule(ctx context.Context, id string, dueDate string) (err error) {
+	return t.RescheduleTodosContext(ctx, map[string]string{id: dueDate})
+}
+
+// RescheduleTodos moves every todo to its due date, in a single request in
+// sync mode.
+func (t *Todoist) RescheduleTodos(dueDates map[string]string) (err error) {
+	return t.RescheduleTodosContext(context.Background(), dueDates)
+}
+
+func (t *Todoist) RescheduleTodosContext(ctx context.Context, dueDates map[string]string) (err error) {
+	if t.apiKey == "" {
+		return ErrNotInitialized
+	}
+	err = t.refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	var commands []command
+	for id, dueDate := range dueDates {
+		todo, err := t.getTodo(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		fields := map[string]any{"due_date": dueDate}
+		labels := withoutLegacyLabels(todo)
+		if len(labels) != len(todo.Labels) {
+			fields["labels"] = labels
+		}
+		if t.SyncMode {
+			commands = append(commands, updateCommand(id, fields))
+			continue
+		}
+		err = t.updateTodo(ctx, id, fields)
+		if err != nil {
+			return err
+		}
+		todo.Labels = labels
+		todo.Due = &Due{Date: &dueDate}
+		t.remember(todo)
+	}
+	if len(commands) > 0 {
+		_, err = t.sync(ctx, commands)
+	}
+	return
+}
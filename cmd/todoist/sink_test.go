@@ -0,0 +1,125 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+func TestTodoistSink(t *testing.T) {
+	id := "12345"
+	ctx := context.Background()
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertEqualString := func(t testing.TB, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	t.Run("It should create an item scheduled from its due date", func(t *testing.T) {
+		var posted Task
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodPost {
+				data, _ := io.ReadAll(req.Body)
+				json.Unmarshal(data, &posted)
+				posted.Id = &id
+				data, _ = json.Marshal(posted)
+				rw.Write(data)
+				return
+			}
+			data, _ := json.Marshal([]Task{})
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		startDate := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+		created, err := todoist.Create(ctx, sink.Item{Title: "foo", Url: "https://foo.dev", DueDate: startDate, Priority: 3})
+
+		assertNoError(t, err)
+		assertEqualString(t, created.Id, id)
+		assertEqualString(t, created.Url, "https://foo.dev")
+		assertEqualString(t, created.DueDate, startDate)
+		if created.Priority != 3 {
+			t.Fatalf("got priority %d, want 3", created.Priority)
+		}
+	})
+
+	t.Run("It should list project todos as items", func(t *testing.T) {
+		title := "foo"
+		url := "https://foo.dev"
+		date := "2026-10-16"
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			assertEqualString(t, req.URL.Query().Get("project_id"), id)
+			data, _ := json.Marshal([]Task{{Id: &id, Content: &title, Description: &url, Due: &Due{Date: &date}}})
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		items, err := todoist.List(ctx)
+
+		assertNoError(t, err)
+		if len(items) != 1 {
+			t.Fatalf("got %d items, want 1", len(items))
+		}
+		assertEqualString(t, items[0].Title, title)
+		assertEqualString(t, items[0].Url, url)
+		assertEqualString(t, items[0].DueDate, date)
+		assertEqualString(t, string(items[0].Status), string(sink.StatusOpen))
+	})
+
+	t.Run("It should drop the legacy date label when rescheduling", func(t *testing.T) {
+		var update map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			assertEqualString(t, req.URL.Path, "/tasks/"+id)
+			if req.Method == http.MethodPost {
+				data, _ := io.ReadAll(req.Body)
+				json.Unmarshal(data, &update)
+				rw.Write(data)
+				return
+			}
+			data, _ := json.Marshal(Task{Id: &id, Labels: []string{"foo", "2026-10-16"}})
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		err := todoist.Reschedule(ctx, id, "2026-10-20")
+
+		assertNoError(t, err)
+		assertEqualString(t, update["due_date"].(string), "2026-10-20")
+		labels := update["labels"].([]any)
+		if len(labels) != 1 || labels[0] != "foo" {
+			t.Fatalf("got labels %v, want [foo]", labels)
+		}
+	})
+
+	t.Run("It should report missing item with sink error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			data, _ := json.Marshal([]Task{})
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		_, err := todoist.FindByUrl(ctx, "https://foo.dev")
+		if err != sink.ErrNotFound {
+			t.Fatalf("got %v, want %v", err, sink.ErrNotFound)
+		}
+	})
+}
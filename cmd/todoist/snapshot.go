@@ -0,0 +1,124 @@
+package todoist
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// local tells if the todos are looked up in the local copy of the project
+// instead of through the api.
+func (t *Todoist) local() bool {
+	return t.SyncMode || t.SnapshotInterval > 0
+}
+
+// refresh brings the local copy of the project up to date: the changes since
+// the last sync in sync mode, a new snapshot when the current one is too old
+// in snapshot mode.
+func (t *Todoist) refresh(ctx context.Context) (err error) {
+	if t.SyncMode {
+		_, err = t.sync(ctx, nil)
+		return
+	}
+	if t.SnapshotInterval <= 0 {
+		return
+	}
+
+	t.tasksMutex.Lock()
+	fresh := time.Since(t.snapshotAt) < t.SnapshotInterval
+	t.tasksMutex.Unlock()
+	if fresh {
+		return
+	}
+	return t.takeSnapshot(ctx)
+}
+
+// forceRefresh reads the project again, the local copy disagrees with what
+// is asked and may be stale.
+func (t *Todoist) forceRefresh(ctx context.Context) (err error) {
+	if t.SyncMode {
+		_, err = t.sync(ctx, nil)
+		return
+	}
+	return t.takeSnapshot(ctx)
+}
+
+func (t *Todoist) takeSnapshot(ctx context.Context) (err error) {
+	url := fmt.Sprintf("%s/tasks?project_id=%s", t.baseUrl, t.projectId)
+	todos, err := t.getTodos(ctx, url)
+	if err != nil {
+		return
+	}
+
+	tasks := make(map[string]Task, len(todos))
+	for _, todo := range todos {
+		if todo.Id != nil {
+			tasks[*todo.Id] = todo
+		}
+	}
+
+	t.tasksMutex.Lock()
+	defer t.tasksMutex.Unlock()
+	t.tasks = tasks
+	t.snapshotAt = time.Now()
+	return
+}
+
+// remember updates the snapshot after a write, the sync mode reads its own
+// writes back from the sync answer.
+func (t *Todoist) remember(todo Task) {
+	if t.SyncMode || t.SnapshotInterval <= 0 || todo.Id == nil {
+		return
+	}
+
+	t.tasksMutex.Lock()
+	defer t.tasksMutex.Unlock()
+	if t.tasks != nil {
+		t.tasks[*todo.Id] = todo
+	}
+}
+
+func (t *Todoist) forget(id string) {
+	if t.SyncMode || t.SnapshotInterval <= 0 {
+		return
+	}
+
+	t.tasksMutex.Lock()
+	defer t.tasksMutex.Unlock()
+	delete(t.tasks, id)
+}
+
+// localTodos returns the open todos of the project matching match, in the
+// order of their ids.
+func (t *Todoist) localTodos(match func(Task) bool) (todos []Task) {
+	t.tasksMutex.Lock()
+	defer t.tasksMutex.Unlock()
+
+	for _, todo := range t.tasks {
+		if match(todo) {
+			todos = append(todos, todo)
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool { return *todos[i].Id < *todos[j].Id })
+	return
+}
+
+func (t *Todoist) localTodo(id string) (todo Task, err error) {
+	t.tasksMutex.Lock()
+	defer t.tasksMutex.Unlock()
+
+	todo, ok := t.tasks[id]
+	if !ok {
+		return todo, ErrTodoNotFound
+	}
+	return
+}
+
+// hasUrl matches the todos whose description is the same page as url.
+func hasUrl(url string) func(Task) bool {
+	canonical := canonicalUrl(url)
+	return func(todo Task) bool {
+		return canonical != "" && todo.Description != nil && canonicalUrl(*todo.Description) == canonical
+	}
+}
@@ -0,0 +1,141 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProject serves the tasks routes of the rest api and counts the
+// listings made.
+type fakeProject struct {
+	mutex    sync.Mutex
+	listings int
+	nextId   int
+	tasks    []Task
+}
+
+func (f *fakeProject) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/tasks":
+		f.listings++
+		json.NewEncoder(rw).Encode(f.tasks)
+	case req.Method == http.MethodPost && req.URL.Path == "/tasks":
+		var todo Task
+		data, _ := io.ReadAll(req.Body)
+		json.Unmarshal(data, &todo)
+		f.nextId++
+		id := fmt.Sprint(f.nextId)
+		todo.Id = &id
+		f.tasks = append(f.tasks, todo)
+		json.NewEncoder(rw).Encode(todo)
+	case req.Method == http.MethodDelete:
+		f.remove(strings.TrimPrefix(req.URL.Path, "/tasks/"))
+	default:
+		rw.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeProject) remove(id string) {
+	for i, task := range f.tasks {
+		if *task.Id == id {
+			f.tasks = append(f.tasks[:i], f.tasks[i+1:]...)
+			return
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	today := time.Now().Format("2006-01-02")
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertEqualInt := func(t testing.TB, got, want int) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+	}
+
+	newTodoist := func(t testing.TB, interval time.Duration, dueDates ...string) (*Todoist, *fakeProject) {
+		t.Helper()
+		fake := &fakeProject{}
+		for _, dueDate := range dueDates {
+			fake.nextId++
+			id, date := fmt.Sprint(fake.nextId), dueDate
+			fake.tasks = append(fake.tasks, Task{Id: &id, Due: &Due{Date: &date}})
+		}
+		server := httptest.NewServer(fake)
+		t.Cleanup(server.Close)
+
+		todoist := &Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: "12345", MaxTodoPerDay: 2, SnapshotInterval: interval}
+		return todoist, fake
+	}
+
+	t.Run("It should schedule and dedup against the snapshot", func(t *testing.T) {
+		todoist, fake := newTodoist(t, time.Hour, today)
+
+		created, err := todoist.CreateTodoWithOptions("foo", "https://foo.dev", TodoOptions{})
+		assertNoError(t, err)
+		if *created.DueDate != today {
+			t.Fatalf("got %s, want %s", *created.DueDate, today)
+		}
+		created, err = todoist.CreateTodoWithOptions("bar", "https://bar.dev", TodoOptions{})
+		assertNoError(t, err)
+		if *created.DueDate != tomorrow {
+			t.Fatalf("the snapshot should count the todo just created, got %s", *created.DueDate)
+		}
+		assertEqualInt(t, fake.listings, 1)
+
+		_, err = todoist.CreateTodoWithOptions("foo", "https://foo.dev", TodoOptions{})
+		if err != ErrAlreadyExist {
+			t.Fatalf("got %v, want %v", err, ErrAlreadyExist)
+		}
+		assertEqualInt(t, fake.listings, 2)
+	})
+
+	t.Run("It should refresh the snapshot on conflicts", func(t *testing.T) {
+		todoist, fake := newTodoist(t, time.Hour, today)
+		description := "https://foo.dev/?utm_source=rss"
+		fake.tasks[0].Description = &description
+		_, err := todoist.FindTodo("https://bar.dev")
+		if err != ErrTodoNotFound {
+			t.Fatalf("got %v, want %v", err, ErrTodoNotFound)
+		}
+
+		fake.remove("1")
+		_, err = todoist.CreateTodoWithOptions("foo", "https://foo.dev", TodoOptions{})
+		assertNoError(t, err)
+		assertEqualInt(t, fake.listings, 3)
+	})
+
+	t.Run("It should forget deleted todos and refresh when too old", func(t *testing.T) {
+		todoist, fake := newTodoist(t, time.Hour, today, today)
+		assertNoError(t, todoist.refresh(ctx))
+		assertNoError(t, todoist.DeleteTodo("1"))
+		perDay, err := todoist.todosPerDay(ctx)
+		assertNoError(t, err)
+		assertEqualInt(t, perDay[today], 1)
+
+		todoist.SnapshotInterval = time.Nanosecond
+		assertNoError(t, todoist.refresh(ctx))
+		assertEqualInt(t, fake.listings, 2)
+	})
+}
@@ -0,0 +1,201 @@
+package todoist
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MAX_COMMANDS is the number of commands the sync api accepts per request.
+const MAX_COMMANDS = 100
+
+var ErrSyncCommand = errors.New("todoist sync command failed")
+
+type command struct {
+	Type   string         `json:"type"`
+	Uuid   string         `json:"uuid"`
+	TempId string         `json:"temp_id,omitempty"`
+	Args   map[string]any `json:"args"`
+}
+
+type syncItem struct {
+	Task
+	IsDeleted bool `json:"is_deleted"`
+}
+
+type syncAnswer struct {
+	SyncToken     string                     `json:"sync_token"`
+	FullSync      bool                       `json:"full_sync"`
+	Items         []syncItem                 `json:"items"`
+	SyncStatus    map[string]json.RawMessage `json:"sync_status"`
+	TempIdMapping map[string]string          `json:"temp_id_mapping"`
+}
+
+func newUuid() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func newCommand(kind string, args map[string]any) command {
+	return command{Type: kind, Uuid: newUuid(), Args: args}
+}
+
+// addCommand adds todo under a temp id, so the commands of the same batch
+// can refer to it before it has a real id.
+func addCommand(todo Task) command {
+	args := map[string]any{"content": todo.Content}
+	if len(todo.Labels) > 0 {
+		args["labels"] = todo.Labels
+	}
+	if todo.ProjectId != nil {
+		args["project_id"] = *todo.ProjectId
+	}
+	if todo.SectionId != nil {
+		args["section_id"] = *todo.SectionId
+	}
+	if todo.ParentId != nil {
+		args["parent_id"] = *todo.ParentId
+	}
+	if todo.Description != nil {
+		args["description"] = *todo.Description
+	}
+	if todo.DueDate != nil {
+		args["due"] = map[string]string{"date": *todo.DueDate}
+	}
+	if todo.Priority != 0 {
+		args["priority"] = todo.Priority
+	}
+	add := newCommand("item_add", args)
+	add.TempId = newUuid()
+	return add
+}
+
+// sync sends the commands and applies the changes made since the last
+// sync to the local copy of the project. Without commands it only reads
+// the changes.
+func (t *Todoist) sync(ctx context.Context, commands []command) (tempIds map[string]string, err error) {
+	t.tasksMutex.Lock()
+	defer t.tasksMutex.Unlock()
+
+	tempIds = map[string]string{}
+	for start := 0; start == 0 || start < len(commands); start += MAX_COMMANDS {
+		batch := commands[start:min(start+MAX_COMMANDS, len(commands))]
+		for _, command := range batch {
+			// temp ids are only known within their own request
+			if parentId, ok := command.Args["parent_id"].(string); ok && tempIds[parentId] != "" {
+				command.Args["parent_id"] = tempIds[parentId]
+			}
+		}
+		answer, err := t.syncBatch(ctx, batch)
+		if err != nil {
+			return tempIds, err
+		}
+		for tempId, id := range answer.TempIdMapping {
+			tempIds[tempId] = id
+		}
+	}
+	return
+}
+
+func (t *Todoist) syncBatch(ctx context.Context, commands []command) (answer syncAnswer, err error) {
+	syncToken := t.syncToken
+	if syncToken == "" {
+		syncToken = "*"
+	}
+	form := url.Values{
+		"sync_token":     {syncToken},
+		"resource_types": {`["items"]`},
+	}
+	if len(commands) > 0 {
+		data, err := json.Marshal(commands)
+		if err != nil {
+			return answer, err
+		}
+		form.Set("commands", string(data))
+	}
+
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/sync", t.baseUrl), strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := t.do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	responseData, err := io.ReadAll(response.Body)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(responseData, &answer)
+	if err != nil {
+		return
+	}
+
+	if answer.FullSync || t.tasks == nil {
+		t.tasks = map[string]Task{}
+	}
+	for _, item := range answer.Items {
+		if item.Id == nil {
+			continue
+		}
+		if item.IsDeleted || item.Checked || item.ProjectId == nil || *item.ProjectId != t.projectId {
+			delete(t.tasks, *item.Id)
+			continue
+		}
+		t.tasks[*item.Id] = item.Task
+	}
+	t.syncToken = answer.SyncToken
+
+	var errs []error
+	for _, command := range commands {
+		status, ok := answer.SyncStatus[command.Uuid]
+		if !ok || string(status) == `"ok"` {
+			continue
+		}
+		var refused struct {
+			HttpCode int `json:"http_code"`
+		}
+		json.Unmarshal(status, &refused)
+		if refused.HttpCode == http.StatusNotFound {
+			errs = append(errs, fmt.Errorf("%w: %s %s: %w", ErrSyncCommand, command.Type, status, ErrTodoNotFound))
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%w: %s %s", ErrSyncCommand, command.Type, status))
+	}
+	return answer, errors.Join(errs...)
+}
+
+// syncAdd adds the parent todo and its children in a single request.
+func (t *Todoist) syncAdd(ctx context.Context, parent Task, children []Task) (created Task, createdChildren []Task, err error) {
+	add := addCommand(parent)
+	commands := []command{add}
+	for _, child := range children {
+		child.ParentId = &add.TempId
+		commands = append(commands, addCommand(child))
+	}
+
+	tempIds, err := t.sync(ctx, commands)
+	if err != nil {
+		return
+	}
+	created, err = t.localTodo(tempIds[add.TempId])
+	if err != nil {
+		return
+	}
+	for _, command := range commands[1:] {
+		child, err := t.localTodo(tempIds[command.TempId])
+		if err != nil {
+			return created, createdChildren, err
+		}
+		createdChildren = append(createdChildren, child)
+	}
+	return
+}
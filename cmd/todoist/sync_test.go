@@ -0,0 +1,243 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSync serves the sync endpoint, the sync token is the number of
+// changes made so far.
+type fakeSync struct {
+	mutex    sync.Mutex
+	requests int
+	nextId   int
+	changes  int
+	items    map[string]*syncItem
+	changed  map[string]int
+}
+
+func (f *fakeSync) apply(command command, tempIds map[string]string) (status any) {
+	f.changes++
+	id, _ := command.Args["id"].(string)
+	if command.Type == "item_add" {
+		f.nextId++
+		id = strconv.Itoa(f.nextId)
+		tempIds[command.TempId] = id
+		item := &syncItem{Task: Task{Id: &id}}
+		projectId, _ := command.Args["project_id"].(string)
+		content, _ := command.Args["content"].(string)
+		description, _ := command.Args["description"].(string)
+		item.ProjectId, item.Content, item.Description = &projectId, &content, &description
+		if parentId, ok := command.Args["parent_id"].(string); ok {
+			if tempIds[parentId] != "" {
+				parentId = tempIds[parentId]
+			}
+			item.ParentId = &parentId
+		}
+		f.items[id] = item
+	}
+
+	item, ok := f.items[id]
+	if !ok {
+		return map[string]any{"error_code": 22, "error": "Item not found", "error_tag": "ITEM_NOT_FOUND", "http_code": 404}
+	}
+	switch command.Type {
+	case "item_delete":
+		item.IsDeleted = true
+	case "item_close":
+		item.Checked = true
+	}
+	if labels, ok := command.Args["labels"].([]any); ok {
+		item.Labels = nil
+		for _, label := range labels {
+			item.Labels = append(item.Labels, label.(string))
+		}
+	}
+	if due, ok := command.Args["due"].(map[string]any); ok {
+		date := due["date"].(string)
+		item.Due = &Due{Date: &date}
+	}
+	f.changed[id] = f.changes
+	return "ok"
+}
+
+func (f *fakeSync) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if req.URL.Path != "/sync" || req.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	req.ParseForm()
+	f.requests++
+
+	var commands []command
+	if data := req.PostForm.Get("commands"); data != "" {
+		json.Unmarshal([]byte(data), &commands)
+	}
+	answer := map[string]any{}
+	statuses := map[string]any{}
+	tempIds := map[string]string{}
+	for _, command := range commands {
+		statuses[command.Uuid] = f.apply(command, tempIds)
+	}
+
+	since, err := strconv.Atoi(req.PostForm.Get("sync_token"))
+	fullSync := err != nil
+	items := []syncItem{}
+	for id, item := range f.items {
+		if (fullSync && !item.IsDeleted) || f.changed[id] > since {
+			items = append(items, *item)
+		}
+	}
+	answer["sync_token"] = strconv.Itoa(f.changes)
+	answer["full_sync"] = fullSync
+	answer["items"] = items
+	answer["sync_status"] = statuses
+	answer["temp_id_mapping"] = tempIds
+	json.NewEncoder(rw).Encode(answer)
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	projectId := "12345"
+	today := time.Now().Format("2006-01-02")
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertEqualInt := func(t testing.TB, got, want int) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+	}
+
+	newSynced := func(t testing.TB) (*Todoist, *fakeSync) {
+		t.Helper()
+		otherProject, otherId, content := "other", "100", "elsewhere"
+		fake := &fakeSync{items: map[string]*syncItem{}, changed: map[string]int{}, nextId: 100}
+		fake.items[otherId] = &syncItem{Task: Task{Id: &otherId, ProjectId: &otherProject, Content: &content, Due: &Due{Date: &today}}}
+		server := httptest.NewServer(fake)
+		t.Cleanup(server.Close)
+
+		todoist := &Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: projectId, SyncMode: true}
+		return todoist, fake
+	}
+
+	t.Run("It should read the project once then only its changes", func(t *testing.T) {
+		todoist, fake := newSynced(t)
+
+		created, err := todoist.CreateTodoWithOptions("foo bar", "https://foo.dev", TodoOptions{})
+		assertNoError(t, err)
+		if *created.Id != "101" || *created.Content != "foo bar" || len(created.Labels) != 0 || *created.Due.Date != today {
+			t.Fatalf("unexpected todo %+v", created)
+		}
+		assertEqualInt(t, fake.requests, 2)
+
+		_, err = todoist.CreateTodoWithOptions("foo bar", "https://FOO.dev/#top", TodoOptions{})
+		if err != ErrAlreadyExist {
+			t.Fatalf("got %v, want %v", err, ErrAlreadyExist)
+		}
+		assertEqualInt(t, fake.requests, 3)
+
+		found, err := todoist.FindTodo("https://foo.dev")
+		assertNoError(t, err)
+		if *found.Id != *created.Id {
+			t.Fatalf("got %+v, want %+v", found, created)
+		}
+	})
+
+	t.Run("It should create a group in a single request", func(t *testing.T) {
+		todoist, fake := newSynced(t)
+		items := []TodoItem{{Title: "foo", Description: "https://foo.dev"}, {Title: "bar", Description: "https://bar.dev"}}
+
+		parent, children, err := todoist.CreateTodoGroup("digest", items, TodoOptions{})
+		assertNoError(t, err)
+		assertEqualInt(t, fake.requests, 2)
+		assertEqualInt(t, len(children), 2)
+		for _, child := range children {
+			if *child.ParentId != *parent.Id {
+				t.Fatalf("child %+v should belong to %s", child, *parent.Id)
+			}
+		}
+	})
+
+	t.Run("It should put the configured labels on the todos created", func(t *testing.T) {
+		todoist, fake := newSynced(t)
+		todoist.Labels = []string{"news"}
+		items := []TodoItem{{Title: "foo", Description: "https://foo.dev"}}
+
+		_, children, err := todoist.CreateTodoGroup("digest", items, TodoOptions{})
+		assertNoError(t, err)
+		for _, id := range []string{*children[0].ParentId, *children[0].Id} {
+			labels := fake.items[id].Labels
+			if len(labels) != 1 || labels[0] != "news" {
+				t.Fatalf("todo %s should be labeled news but got %v", id, labels)
+			}
+		}
+	})
+
+	t.Run("It should import and reschedule todos in batches", func(t *testing.T) {
+		todoist, fake := newSynced(t)
+		todoist.MaxTodoPerDay = 2
+		var items []TodoItem
+		for i := 0; i < 5; i++ {
+			items = append(items, TodoItem{Title: fmt.Sprint("news ", i), Description: fmt.Sprint("https://", i, ".dev")})
+		}
+		items = append(items, items[0])
+
+		created, err := todoist.CreateTodos(items, TodoOptions{})
+		assertNoError(t, err)
+		assertEqualInt(t, len(created), 5)
+		assertEqualInt(t, fake.requests, 2)
+		perDay := map[string]int{}
+		for _, todo := range created {
+			perDay[*todo.Due.Date]++
+		}
+		if perDay[today] != 2 || len(perDay) != 3 {
+			t.Fatalf("todos of other projects should not take room, got %v", perDay)
+		}
+
+		later := time.Now().AddDate(0, 0, 10).Format("2006-01-02")
+		dueDates := map[string]string{}
+		for _, todo := range created {
+			dueDates[*todo.Id] = later
+		}
+		assertNoError(t, todoist.RescheduleTodos(dueDates))
+		assertEqualInt(t, fake.requests, 4)
+		perDay, err = todoist.todosPerDay(ctx)
+		assertNoError(t, err)
+		assertEqualInt(t, perDay[later], 5)
+
+		assertNoError(t, todoist.CloseTodo(*created[0].Id))
+		assertNoError(t, todoist.DeleteTodo(*created[1].Id))
+		todos, err := todoist.projectTodos(ctx)
+		assertNoError(t, err)
+		assertEqualInt(t, len(todos), 3)
+	})
+
+	t.Run("It should report the commands refused", func(t *testing.T) {
+		todoist, _ := newSynced(t)
+		err := todoist.DeleteTodo("999")
+		if !errors.Is(err, ErrSyncCommand) {
+			t.Fatalf("got %v, want %v", err, ErrSyncCommand)
+		}
+		if !errors.Is(err, ErrTodoNotFound) {
+			t.Errorf("got %v, want %v", err, ErrTodoNotFound)
+		}
+	})
+}
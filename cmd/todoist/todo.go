@@ -2,84 +2,199 @@ package todoist
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/helpers"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/httpapi"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/schedule"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
 )
 
 const (
-	BASE_URL            = "https://api.todoist.com/rest/v2"
-	API_KEY             = "API_KEY"
-	MAX_TODO_PER_DAY    = 5
-	MAX_DAYS_TO_LOOK_UP = 30
+	BASE_URL            = "https://api.todoist.com/api/v1"
+	REST_V2_BASE_URL    = "https://api.todoist.com/rest/v2"
+	API_VERSION_V1      = "v1"
+	API_VERSION_REST_V2 = "rest/v2"
+	MAX_TODO_PER_DAY    = schedule.MAX_PER_DAY
+	MAX_DAYS_TO_LOOK_UP = schedule.MAX_DAYS_TO_LOOK_UP
 )
 
 var (
-	ErrProjectNotFound         = errors.New("todoist project not found")
-	ErrNotInitialized          = errors.New("todoist object not initialized, call init method")
-	ErrHttpRequestDefault      = errors.New("error on todoist api call")
-	ErrHttpRequestUnauthorized = errors.New("unauthorized todoist access")
-	ErrAlreadyExist            = errors.New("todo already exist")
+	ErrProjectNotFound         = sink.NewPermanentError("todoist project not found")
+	ErrNotInitialized          = sink.NewPermanentError("todoist object not initialized, call init method")
+	ErrUnknownApiVersion       = errors.New("unknown todoist api version")
+	ErrHttpRequestDefault      = httpapi.NewError("error on todoist api call", httpapi.ErrApiCall)
+	ErrHttpRequestUnauthorized = httpapi.NewError("unauthorized todoist access", httpapi.ErrUnauthorized)
+	ErrAlreadyExist            = sink.ErrAlreadyExist
+	ErrTodoNotFound            = sink.ErrNotFound
 )
 
+type TodoOptions struct {
+	Priority int
+	// StartDate is the first day the todo can be scheduled on, today when zero.
+	StartDate time.Time
+	// SectionId is the section of the project the todo goes to, the
+	// project root when empty.
+	SectionId string
+}
+
+func (o TodoOptions) startDate() time.Time {
+	if o.StartDate.IsZero() {
+		return time.Now()
+	}
+	return o.StartDate
+}
+
+func (o TodoOptions) sectionId() *string {
+	if o.SectionId == "" {
+		return nil
+	}
+	return &o.SectionId
+}
+
+type TodoItem struct {
+	Title       string
+	Description string
+}
+
 type Todoist struct {
 	baseUrl     string
 	Client      *http.Client
+	ApiKey      string
 	ProjectName string
-
-	apiKey    string
-	projectId string
+	// ApiVersion selects the api talked to, API_VERSION_V1 when empty.
+	ApiVersion string
+	// MaxTodoPerDay and MaxDaysToLookUp default to MAX_TODO_PER_DAY and
+	// MAX_DAYS_TO_LOOK_UP when left empty.
+	MaxTodoPerDay   int
+	MaxDaysToLookUp int
+	// SlotPerLink makes every subtask of a group count in the daily
+	// capacity, otherwise the whole group takes a single slot.
+	SlotPerLink bool
+	// SyncMode reads the project through the sync api, once and then
+	// incrementally, and sends the writes in batches of commands.
+	SyncMode bool
+	// SnapshotInterval keeps a snapshot of the open todos of the project,
+	// read again when older than the interval, so capacity and duplicate
+	// checks don't need a request per day. Disabled when zero.
+	SnapshotInterval time.Duration
+	// Bootstrap creates on Init the project, the Sections and the Labels
+	// missing instead of failing with ErrProjectNotFound.
+	Bootstrap bool
+	Sections  []string
+	// Labels are put on every todo created.
+	Labels []string
+	// Routes sends the todos to a section of the project, resolved by name
+	// on Init. Todos matching no route stay at the project root.
+	Routes []Route
+	// CompletedLookback also dedups against the todos completed within it,
+	// only with the api v1. Disabled when zero.
+	CompletedLookback time.Duration
+	// Retry sends again the calls failing for a transient reason, none
+	// when nil.
+	Retry *httpapi.Retry
+
+	apiKey     string
+	projectId  string
+	sectionIds map[string]string
+
+	// tasks is the local copy of the open todos, in sync or snapshot mode
+	tasksMutex sync.Mutex
+	tasks      map[string]Task
+	syncToken  string
+	snapshotAt time.Time
+
+	// completed caches the todos completed within CompletedLookback, listed
+	// up to completedUntil
+	completedMutex sync.Mutex
+	completed      []Task
+	completedUntil time.Time
 }
 
 func (t *Todoist) Init(projectName string) (err error) {
-	key := fmt.Sprintf("$%s", API_KEY)
-	t.apiKey = os.ExpandEnv(key)
+	return t.InitContext(context.Background(), projectName)
+}
+
+// InitContext is Init with a context cancelling the project lookup.
+func (t *Todoist) InitContext(ctx context.Context, projectName string) (err error) {
+	t.apiKey = t.ApiKey
 
 	t.ProjectName = projectName
 	if t.baseUrl == "" {
-		t.baseUrl = BASE_URL
+		t.baseUrl, err = baseUrlOf(t.ApiVersion)
+		if err != nil {
+			return
+		}
 	}
-	t.projectId, err = t.getProjectId(projectName)
-	return
+	if t.Bootstrap {
+		err = t.bootstrap(ctx, projectName)
+	} else {
+		t.projectId, err = t.getProjectId(ctx, projectName)
+	}
+	if err != nil {
+		return
+	}
+	return t.resolveSections(ctx)
 }
 
-func verifyErrorInAnswer(response *http.Response) (err error) {
-	if response.StatusCode == http.StatusOK {
-		return nil
-	}
-	if response.StatusCode == http.StatusUnauthorized {
-		return ErrHttpRequestUnauthorized
+func baseUrlOf(apiVersion string) (baseUrl string, err error) {
+	switch apiVersion {
+	case "", API_VERSION_V1:
+		return BASE_URL, nil
+	case API_VERSION_REST_V2:
+		return REST_V2_BASE_URL, nil
 	}
-	responseData, _ := io.ReadAll(response.Body)
-	return fmt.Errorf("%w: %s", ErrHttpRequestDefault, string(responseData))
+	return "", fmt.Errorf("%w %q", ErrUnknownApiVersion, apiVersion)
 }
 
-func doHttpRequest(request *http.Request, client *http.Client, apiKey string) (response *http.Response, err error) {
+var apiErrors = httpapi.Errors{
+	Default:      ErrHttpRequestDefault,
+	Unauthorized: ErrHttpRequestUnauthorized,
+	ByStatus:     map[int]error{http.StatusNotFound: ErrTodoNotFound},
+}
+
+// REQUEST_ID_HEADER makes a POST idempotent, todoist ignores the requests
+// with an id already seen.
+const REQUEST_ID_HEADER = "X-Request-Id"
+
+// NewRetry returns the retry policy of the todoist api, POSTs are sent again
+// with the same request id.
+func NewRetry() *httpapi.Retry {
+	return &httpapi.Retry{IdempotencyHeader: REQUEST_ID_HEADER, Breaker: &httpapi.Breaker{}}
+}
+
+func doHttpRequest(request *http.Request, client *http.Client, apiKey string, retry *httpapi.Retry) (response *http.Response, err error) {
 	bearer := fmt.Sprintf("Bearer %s", apiKey)
 	request.Header.Set("Authorization", bearer)
-	request.Header.Set("Content-Type", "application/json")
-
-	response, err = client.Do(request)
-	if err != nil {
-		return
+	if request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if request.Method == http.MethodPost && request.Header.Get(REQUEST_ID_HEADER) == "" {
+		request.Header.Set(REQUEST_ID_HEADER, newUuid())
 	}
 
-	err = verifyErrorInAnswer(response)
-	if err != nil {
-		return
+	if retry == nil {
+		return apiErrors.Do(client, request)
 	}
+	return retry.Do(apiErrors, client, request)
+}
 
-	return
+func (t *Todoist) do(request *http.Request) (response *http.Response, err error) {
+	return doHttpRequest(request, t.Client, t.apiKey, t.Retry)
 }
 
-func (t *Todoist) getProjectId(projectName string) (projectId string, err error) {
-	projects, err := t.getProjects()
+func (t *Todoist) getProjectId(ctx context.Context, projectName string) (projectId string, err error) {
+	projects, err := t.getProjects(ctx)
 	if err != nil {
 		return
 	}
@@ -98,120 +213,566 @@ func (t *Todoist) getProjectId(projectName string) (projectId string, err error)
 	return
 }
 
-func (t *Todoist) getProjects() (projects []Project, err error) {
-	url := fmt.Sprintf("%s/projects", t.baseUrl)
-	request, _ := http.NewRequest(http.MethodGet, url, nil)
+// page is a page of an api v1 listing, the rest v2 api answers the whole
+// listing as an array instead. The completed todos come as items.
+type page[T any] struct {
+	Results    []T     `json:"results"`
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
 
-	response, err := doHttpRequest(request, t.Client, t.apiKey)
-	if err != nil {
-		return
-	}
-	defer response.Body.Close()
+// getAll reads every page of the listing at url, following next_cursor.
+func getAll[T any](ctx context.Context, t *Todoist, listingUrl string) (all []T, err error) {
+	cursor := ""
+	for {
+		pageUrl := listingUrl
+		if cursor != "" {
+			separator := "?"
+			if strings.Contains(listingUrl, "?") {
+				separator = "&"
+			}
+			pageUrl += separator + "cursor=" + url.QueryEscape(cursor)
+		}
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, pageUrl, nil)
 
-	responseData, err := io.ReadAll(response.Body)
-	if err != nil {
-		return
+		response, err := t.do(request)
+		if err != nil {
+			return nil, err
+		}
+		responseData, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if trimmed := bytes.TrimSpace(responseData); len(trimmed) > 0 && trimmed[0] == '[' {
+			var results []T
+			err = json.Unmarshal(responseData, &results)
+			return append(all, results...), err
+		}
+
+		var current page[T]
+		err = json.Unmarshal(responseData, &current)
+		if err != nil {
+			return nil, err
+		}
+		all = append(append(all, current.Results...), current.Items...)
+		if current.NextCursor == nil || *current.NextCursor == "" {
+			return all, nil
+		}
+		cursor = *current.NextCursor
 	}
+}
 
-	err = json.Unmarshal(responseData, &projects)
+func (t *Todoist) getProjects(ctx context.Context) (projects []Project, err error) {
+	return getAll[Project](ctx, t, fmt.Sprintf("%s/projects", t.baseUrl))
+}
 
-	return
+func (t *Todoist) getTodos(ctx context.Context, url string) (todos []Task, err error) {
+	return getAll[Task](ctx, t, url)
 }
 
-func (t *Todoist) getTodosByLabel(label string) (todos []Task, err error) {
-	url := fmt.Sprintf("%s/tasks?project_id=%s&label=%s", t.baseUrl, t.projectId, label)
-	request, _ := http.NewRequest(http.MethodGet, url, nil)
+func (t *Todoist) defineDueDate(ctx context.Context, currentDate time.Time) (dueDateFormated string, err error) {
+	return t.defineDueDateForSlots(ctx, currentDate, 1)
+}
 
-	response, err := doHttpRequest(request, t.Client, t.apiKey)
+// defineDueDateForSlots looks for the first day with enough room for slots
+// todos, a day without any todo always fits.
+func (t *Todoist) defineDueDateForSlots(ctx context.Context, currentDate time.Time, slots int) (dueDateFormated string, err error) {
+	perDay, err := t.todosPerDay(ctx)
 	if err != nil {
 		return
 	}
-	defer response.Body.Close()
+	return t.firstFreeDay(perDay, currentDate, slots)
+}
 
-	responseData, err := io.ReadAll(response.Body)
+// firstFreeDay looks for the first day from currentDate with room for slots
+// todos, perDay being the todos already scheduled on each day.
+func (t *Todoist) firstFreeDay(perDay map[string]int, currentDate time.Time, slots int) (dueDateFormated string, err error) {
+	capacity := schedule.Capacity{MaxPerDay: t.MaxTodoPerDay, MaxDaysToLookUp: t.MaxDaysToLookUp}.WithDefaults()
+	return capacity.FirstFreeDay(currentDate, slots, func(date string) (count int, err error) {
+		return perDay[date], nil
+	})
+}
+
+// openTodos returns the open todos of the project, from the local copy when
+// there is one. Unlike projectTodos, the local copy is not refreshed, the
+// creations refresh it first.
+func (t *Todoist) openTodos(ctx context.Context) (todos []Task, err error) {
+	if t.local() {
+		return t.localTodos(func(Task) bool { return true }), nil
+	}
+	return t.getTodos(ctx, fmt.Sprintf("%s/tasks?project_id=%s", t.baseUrl, t.projectId))
+}
+
+// todosPerDay counts the open todos of the project by due date, whatever
+// moved them there.
+func (t *Todoist) todosPerDay(ctx context.Context) (perDay map[string]int, err error) {
+	todos, err := t.openTodos(ctx)
 	if err != nil {
 		return
 	}
-
-	err = json.Unmarshal(responseData, &todos)
-	return
+	return t.countPerDay(todos), nil
 }
 
-func (t *Todoist) defineDueDate(currentDate time.Time) (dueDateFormated string, err error) {
-	dueDateFormated = currentDate.Format("2006-01-02")
-
-	for i := 1; i <= MAX_DAYS_TO_LOOK_UP; i++ {
-		todos, err := t.getTodosByLabel(dueDateFormated)
-		if err != nil {
-			return "", err
+// countPerDay counts todos by due date. A group takes a single slot, one per
+// subtask with SlotPerLink.
+func (t *Todoist) countPerDay(todos []Task) (perDay map[string]int) {
+	parents := map[string]bool{}
+	for _, todo := range todos {
+		if todo.ParentId != nil {
+			parents[*todo.ParentId] = true
 		}
-		if len(todos) < MAX_TODO_PER_DAY {
-			return dueDateFormated, err
+	}
+	perDay = map[string]int{}
+	for _, todo := range todos {
+		isChild := todo.ParentId != nil
+		isParent := todo.Id != nil && parents[*todo.Id]
+		if (isChild || isParent) && isChild != t.SlotPerLink {
+			continue
+		}
+		if date := dueDay(todo); date != "" {
+			perDay[date]++
 		}
-		dueDateFormated = currentDate.AddDate(0, 0, i).Format("2006-01-02")
 	}
+	return
+}
 
+// dueDay is the day todo is due, without its time.
+func dueDay(todo Task) (date string) {
+	if todo.Due != nil && todo.Due.Date != nil {
+		date = *todo.Due.Date
+	} else if todo.DueDate != nil {
+		date = *todo.DueDate
+	}
+	if len(date) > len(schedule.DATE_FORMAT) {
+		date = date[:len(schedule.DATE_FORMAT)]
+	}
 	return
 }
 
-func (t *Todoist) createTodoDTO(title, description string) (todo Task, err error) {
-	dueDate, err := t.defineDueDate(time.Now())
-	titleLabel := strings.ReplaceAll(strings.Trim(title, " "), " ", "-")
+// createTodoDTO schedules the todo among the open todos of the project.
+func (t *Todoist) createTodoDTO(todos []Task, title, description string, options TodoOptions) (todo Task, err error) {
+	dueDate, err := t.firstFreeDay(t.countPerDay(todos), options.startDate(), 1)
 	if err != nil {
 		return
 	}
 
-	labels := []string{titleLabel, dueDate}
 	todo = Task{
 		ProjectId:   &t.projectId,
+		SectionId:   options.sectionId(),
 		Content:     &title,
 		Description: &description,
-		Labels:      labels,
 		DueDate:     &dueDate,
+		Priority:    options.Priority,
+		Labels:      t.Labels,
 	}
 	return
 }
 
-func ensureTodoNotAlreadyExist(title string, todoist *Todoist) (err error) {
-	titleLabel := strings.ReplaceAll(strings.Trim(title, " "), " ", "-")
-	todos, err := todoist.getTodosByLabel(titleLabel)
+// canonicalUrl is the key todos are deduplicated on, url itself when it
+// can't be parsed.
+func canonicalUrl(url string) string {
+	canonical, err := helpers.Canonicalize(url)
 	if err != nil {
-		return
+		return url
+	}
+	return canonical
+}
+
+// withUrl returns the todos saving the same page as url.
+func withUrl(todos []Task, url string) (found []Task) {
+	match := hasUrl(url)
+	for _, todo := range todos {
+		if match(todo) {
+			found = append(found, todo)
+		}
+	}
+	return
+}
+
+// ensureTodoNotAlreadyExist looks url up among todos, the open todos of the
+// project listed once for the whole creation.
+func ensureTodoNotAlreadyExist(ctx context.Context, url string, todos []Task, todoist *Todoist) (err error) {
+	todos = withUrl(todos, url)
+	if len(todos) > 0 && todoist.SnapshotInterval > 0 && !todoist.SyncMode {
+		// the snapshot may still have a todo done or deleted since
+		err = todoist.forceRefresh(ctx)
+		if err != nil {
+			return
+		}
+		todos = todoist.localTodos(hasUrl(url))
 	}
 	if len(todos) > 0 {
-		log.Printf("a todo for %s already exist, skip", title)
+		log.Printf("a todo for %s already exist, skip", url)
 		return ErrAlreadyExist
 	}
 	return nil
 }
 
 func (t *Todoist) CreateTodo(title, description string) (err error) {
+	return t.CreateTodoContext(context.Background(), title, description)
+}
+
+func (t *Todoist) CreateTodoContext(ctx context.Context, title, description string) (err error) {
+	_, err = t.CreateTodoWithOptionsContext(ctx, title, description, TodoOptions{})
+	return
+}
+
+func (t *Todoist) CreateTodoWithOptions(title, description string, options TodoOptions) (created Task, err error) {
+	return t.CreateTodoWithOptionsContext(context.Background(), title, description, options)
+}
+
+func (t *Todoist) CreateTodoWithOptionsContext(ctx context.Context, title, description string, options TodoOptions) (created Task, err error) {
 	if t.apiKey == "" {
-		return ErrNotInitialized
+		return created, ErrNotInitialized
 	}
 
-	err = ensureTodoNotAlreadyExist(title, t)
+	err = t.refresh(ctx)
+	if err != nil {
+		return
+	}
+	todos, err := t.openTodos(ctx)
+	if err != nil {
+		return
+	}
+	err = ensureTodoNotAlreadyExist(ctx, description, todos, t)
+	if err != nil {
+		return
+	}
+	read, err := t.alreadyRead(ctx, []string{description})
 	if err != nil {
 		return
 	}
+	if read[description] != nil {
+		return created, read[description]
+	}
 
-	todo, err := t.createTodoDTO(title, description)
+	todo, err := t.createTodoDTO(todos, title, description, options)
 	if err != nil {
 		return
 	}
 
+	return t.postTodo(ctx, todo)
+}
+
+func (t *Todoist) postTodo(ctx context.Context, todo Task) (created Task, err error) {
+	if t.SyncMode {
+		created, _, err = t.syncAdd(ctx, todo, nil)
+		return
+	}
+
 	url := fmt.Sprintf("%s/tasks", t.baseUrl)
 	data, err := json.Marshal(todo)
 	if err != nil {
 		return
 	}
 
-	request, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(data))
-	response, err := doHttpRequest(request, t.Client, t.apiKey)
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(data))
+	response, err := t.do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	responseData, err := io.ReadAll(response.Body)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(responseData, &created)
+	if err != nil {
+		return
+	}
+	t.remember(created)
+	return
+}
+
+// CreateTodoGroup creates a parent todo with one subtask per item. Items
+// that already exist are skipped, ErrAlreadyExist is returned when none is left.
+// Items already read are skipped too, their AlreadyReadError are joined to err.
+func (t *Todoist) CreateTodoGroup(title string, items []TodoItem, options TodoOptions) (parent Task, children []Task, err error) {
+	return t.CreateTodoGroupContext(context.Background(), title, items, options)
+}
+
+func (t *Todoist) CreateTodoGroupContext(ctx context.Context, title string, items []TodoItem, options TodoOptions) (parent Task, children []Task, err error) {
+	if t.apiKey == "" {
+		return parent, nil, ErrNotInitialized
+	}
+
+	err = t.refresh(ctx)
+	if err != nil {
+		return
+	}
+	todos, err := t.openTodos(ctx)
+	if err != nil {
+		return
+	}
+	var remaining []TodoItem
+	for _, item := range items {
+		err = ensureTodoNotAlreadyExist(ctx, item.Description, todos, t)
+		if err == ErrAlreadyExist {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		remaining = append(remaining, item)
+	}
+	remaining, readErr, err := t.skipAlreadyRead(ctx, remaining)
+	if err != nil {
+		return
+	}
+	if len(remaining) == 0 {
+		if readErr != nil {
+			return parent, nil, readErr
+		}
+		return parent, nil, ErrAlreadyExist
+	}
+	defer func() {
+		if err == nil {
+			err = readErr
+		}
+	}()
+
+	slots := 1
+	if t.SlotPerLink {
+		slots = len(remaining)
+	}
+	dueDate, err := t.firstFreeDay(t.countPerDay(todos), options.startDate(), slots)
+	if err != nil {
+		return
+	}
+
+	parentTodo := Task{
+		ProjectId: &t.projectId,
+		SectionId: options.sectionId(),
+		Content:   &title,
+		DueDate:   &dueDate,
+		Priority:  options.Priority,
+		Labels:    t.Labels,
+	}
+	childTodos := make([]Task, 0, len(remaining))
+	for _, item := range remaining {
+		childTodos = append(childTodos, Task{
+			ProjectId:   &t.projectId,
+			SectionId:   options.sectionId(),
+			Content:     &item.Title,
+			Description: &item.Description,
+			DueDate:     &dueDate,
+			Priority:    options.Priority,
+			Labels:      t.Labels,
+		})
+	}
+	if t.SyncMode {
+		return t.syncAdd(ctx, parentTodo, childTodos)
+	}
+
+	parent, err = t.postTodo(ctx, parentTodo)
+	if err != nil {
+		return
+	}
+
+	for _, childTodo := range childTodos {
+		childTodo.ParentId = parent.Id
+		child, err := t.postTodo(ctx, childTodo)
+		if err != nil {
+			return parent, children, err
+		}
+		children = append(children, child)
+	}
+	return
+}
+
+// CreateTodos creates a todo per item not existing yet, each one scheduled
+// on the first day with room left. In sync mode they are all sent at once.
+func (t *Todoist) CreateTodos(items []TodoItem, options TodoOptions) (created []Task, err error) {
+	return t.CreateTodosContext(context.Background(), items, options)
+}
+
+func (t *Todoist) CreateTodosContext(ctx context.Context, items []TodoItem, options TodoOptions) (created []Task, err error) {
+	if t.apiKey == "" {
+		return nil, ErrNotInitialized
+	}
+	err = t.refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	items, readErr, err := t.skipAlreadyRead(ctx, items)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err == nil {
+			err = readErr
+		}
+	}()
+	existing, err := t.openTodos(ctx)
+	if err != nil {
+		return
+	}
+
+	// perDay also counts the todos planned but not sent yet
+	perDay := t.countPerDay(existing)
+	seen := map[string]bool{}
+	var todos []Task
+	for _, item := range items {
+		key := canonicalUrl(item.Description)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		err = ensureTodoNotAlreadyExist(ctx, item.Description, existing, t)
+		if err == ErrAlreadyExist {
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		dueDate, err := t.firstFreeDay(perDay, options.startDate(), 1)
+		if err != nil {
+			return created, err
+		}
+		perDay[dueDate]++
+		todos = append(todos, Task{
+			ProjectId:   &t.projectId,
+			SectionId:   options.sectionId(),
+			Content:     &item.Title,
+			Description: &item.Description,
+			DueDate:     &dueDate,
+			Priority:    options.Priority,
+			Labels:      t.Labels,
+		})
+	}
+
+	if !t.SyncMode {
+		for _, todo := range todos {
+			todo, err := t.postTodo(ctx, todo)
+			if err != nil {
+				return created, err
+			}
+			created = append(created, todo)
+		}
+		return created, nil
+	}
+
+	commands := make([]command, 0, len(todos))
+	for _, todo := range todos {
+		commands = append(commands, addCommand(todo))
+	}
+	tempIds, err := t.sync(ctx, commands)
+	if err != nil {
+		return
+	}
+	for _, command := range commands {
+		todo, err := t.localTodo(tempIds[command.TempId])
+		if err != nil {
+			return created, err
+		}
+		created = append(created, todo)
+	}
+	return
+}
+
+func (t *Todoist) FindTodo(description string) (todo Task, err error) {
+	return t.FindTodoContext(context.Background(), description)
+}
+
+func (t *Todoist) FindTodoContext(ctx context.Context, description string) (todo Task, err error) {
+	if t.apiKey == "" {
+		return todo, ErrNotInitialized
+	}
+
+	todos, err := t.projectTodos(ctx)
+	if err != nil {
+		return
+	}
+	todo, err = findByDescription(todos, description)
+	if err == ErrTodoNotFound && t.SnapshotInterval > 0 && !t.SyncMode {
+		// the todo may have been created since the snapshot
+		err = t.forceRefresh(ctx)
+		if err != nil {
+			return
+		}
+		return findByDescription(t.localTodos(func(Task) bool { return true }), description)
+	}
+	return
+}
+
+// findByDescription finds the todo saving the same page as the url in
+// description.
+func findByDescription(todos []Task, description string) (todo Task, err error) {
+	match := hasUrl(description)
+	for _, current := range todos {
+		if match(current) {
+			return current, nil
+		}
+	}
+	return todo, ErrTodoNotFound
+}
+
+// projectTodos returns every open todo of the project.
+func (t *Todoist) projectTodos(ctx context.Context) (todos []Task, err error) {
+	if t.local() {
+		err = t.refresh(ctx)
+		if err != nil {
+			return
+		}
+		return t.localTodos(func(Task) bool { return true }), nil
+	}
+
+	url := fmt.Sprintf("%s/tasks?project_id=%s", t.baseUrl, t.projectId)
+	return t.getTodos(ctx, url)
+}
+
+func (t *Todoist) DeleteTodo(id string) (err error) {
+	return t.DeleteTodoContext(context.Background(), id)
+}
+
+func (t *Todoist) DeleteTodoContext(ctx context.Context, id string) (err error) {
+	if t.apiKey == "" {
+		return ErrNotInitialized
+	}
+	if t.SyncMode {
+		_, err = t.sync(ctx, []command{newCommand("item_delete", map[string]any{"id": id})})
+		return
+	}
+
+	url := fmt.Sprintf("%s/tasks/%s", t.baseUrl, id)
+	request, _ := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	response, err := t.do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	t.forget(id)
+	return
+}
+
+func (t *Todoist) CloseTodo(id string) (err error) {
+	return t.CloseTodoContext(context.Background(), id)
+}
+
+func (t *Todoist) CloseTodoContext(ctx context.Context, id string) (err error) {
+	if t.apiKey == "" {
+		return ErrNotInitialized
+	}
+	if t.SyncMode {
+		_, err = t.sync(ctx, []command{newCommand("item_close", map[string]any{"id": id})})
+		return
+	}
+
+	url := fmt.Sprintf("%s/tasks/%s/close", t.baseUrl, id)
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	response, err := t.do(request)
 	if err != nil {
 		return
 	}
 	defer response.Body.Close()
 
+	t.forget(id)
 	return
 }
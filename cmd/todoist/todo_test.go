@@ -1,22 +1,21 @@
 package todoist
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
-	"strings"
 	"testing"
 	"time"
-)
 
-type testTitleLabel struct {
-	title      string
-	titleLabel string
-}
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
 
 func TestTodoist(t *testing.T) {
+	ctx := context.Background()
 	id := "12345"
 	name := "Tests"
 	proj := []Project{{
@@ -65,6 +64,15 @@ func TestTodoist(t *testing.T) {
 		}
 	}
 
+	dueOn := func(count int, dates ...string) (todos []Task) {
+		for _, date := range dates {
+			for i := 0; i < count; i++ {
+				todos = append(todos, Task{Due: &Due{Date: &date}})
+			}
+		}
+		return
+	}
+
 	t.Run("It should init all field on init call", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 			data, err := json.Marshal(proj)
@@ -76,8 +84,7 @@ func TestTodoist(t *testing.T) {
 
 		defer server.Close()
 
-		t.Setenv(API_KEY, "XXX")
-		todoist := Todoist{Client: server.Client(), baseUrl: server.URL}
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, ApiKey: "XXX"}
 
 		err := todoist.Init(name)
 
@@ -99,7 +106,7 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL}
-		projId, err := todoist.getProjectId(name)
+		projId, err := todoist.getProjectId(ctx, name)
 
 		assertNoError(t, err)
 		assertEqualString(t, projId, id)
@@ -117,7 +124,7 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL}
-		_, err := todoist.getProjectId("unknown")
+		_, err := todoist.getProjectId(ctx, "unknown")
 
 		assertError(t, err, ErrProjectNotFound)
 	})
@@ -131,7 +138,7 @@ func TestTodoist(t *testing.T) {
 
 		request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
 
-		_, err := doHttpRequest(request, server.Client(), "XXX")
+		_, err := doHttpRequest(request, server.Client(), "XXX", nil)
 		if err == nil {
 			t.Fatal("didn't get an error but wanted one")
 		}
@@ -145,7 +152,7 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL}
-		_, err := todoist.getProjectId(name)
+		_, err := todoist.getProjectId(ctx, name)
 
 		assertError(t, err, ErrHttpRequestUnauthorized)
 	})
@@ -158,7 +165,7 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL}
-		_, err := todoist.getProjectId(name)
+		_, err := todoist.getProjectId(ctx, name)
 
 		assertEqualString(t, err.Error(), fmt.Sprintf("%s: %s", ErrHttpRequestDefault.Error(), "oups\n"))
 	})
@@ -223,9 +230,7 @@ func TestTodoist(t *testing.T) {
 
 	t.Run("It should retrieve a due date for today when today todos are less than MAX_TODO_PER_DAY", func(t *testing.T) {
 		dateFormated := "1970-01-01"
-		todos := []Task{
-			{}, {},
-		}
+		todos := dueOn(2, dateFormated)
 		date, err := time.Parse("2006-01-02", dateFormated)
 		assertNoError(t, err)
 
@@ -239,7 +244,7 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX"}
-		dueDate, err := todoist.defineDueDate(date)
+		dueDate, err := todoist.defineDueDate(ctx, date)
 
 		assertNoError(t, err)
 		assertEqualString(t, dueDate, dateFormated)
@@ -247,15 +252,12 @@ func TestTodoist(t *testing.T) {
 
 	t.Run("It should retrieve a due date for next day when today todos have already MAX_TODO_PER_DAY", func(t *testing.T) {
 		dateFormated := "1970-01-01"
-		todos := []Task{{}, {}, {}, {}, {}}
+		todos := append(dueOn(5, dateFormated), dueOn(4, "1970-01-02T10:00:00")...)
 		date, err := time.Parse("2006-01-02", dateFormated)
 		assertNoError(t, err)
 
 		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-			data, err := json.Marshal([]Task{})
-			if strings.Contains(req.URL.RawQuery, dateFormated) {
-				data, err = json.Marshal(todos)
-			}
+			data, err := json.Marshal(todos)
 			if err != nil {
 				t.Fatal("can't marshal json for testserver answer")
 			}
@@ -264,7 +266,7 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX"}
-		dueDate, err := todoist.defineDueDate(date)
+		dueDate, err := todoist.defineDueDate(ctx, date)
 
 		assertNoError(t, err)
 		assertEqualString(t, dueDate, date.AddDate(0, 0, 1).Format("2006-01-02"))
@@ -272,9 +274,12 @@ func TestTodoist(t *testing.T) {
 
 	t.Run("It should retrieve a due date for a maximum of MAX_DAYS_TO_LOOK_UP", func(t *testing.T) {
 		dateFormated := "1970-01-01"
-		todos := []Task{{}, {}, {}, {}, {}}
 		date, err := time.Parse("2006-01-02", dateFormated)
 		assertNoError(t, err)
+		var todos []Task
+		for i := 0; i <= MAX_DAYS_TO_LOOK_UP; i++ {
+			todos = append(todos, dueOn(5, date.AddDate(0, 0, i).Format("2006-01-02"))...)
+		}
 
 		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 			data, err := json.Marshal(todos)
@@ -286,7 +291,7 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX"}
-		dueDate, err := todoist.defineDueDate(date)
+		dueDate, err := todoist.defineDueDate(ctx, date)
 
 		assertNoError(t, err)
 		assertEqualString(t, dueDate, date.AddDate(0, 0, MAX_DAYS_TO_LOOK_UP).Format("2006-01-02"))
@@ -308,49 +313,43 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
-		got, err := todoist.createTodoDTO(title, description)
+		todos, err := todoist.openTodos(ctx)
+		assertNoError(t, err)
+		got, err := todoist.createTodoDTO(todos, title, description, TodoOptions{})
 
 		assertNoError(t, err)
 		assertEqualString(t, *got.ProjectId, id)
 		assertEqualString(t, *got.Content, title)
 		assertEqualString(t, *got.Description, description)
-		if len(got.Labels) != 2 {
-			t.Fatalf("todo should have 2 labels but got %d", len(got.Labels))
+		if len(got.Labels) != 0 {
+			t.Fatalf("todo should not have labels but got %v", got.Labels)
 		}
-		assertEqualString(t, got.Labels[0], title)
-		assertEqualString(t, got.Labels[1], dueDateFormated)
+		assertEqualString(t, *got.DueDate, dueDateFormated)
 	})
 
-	t.Run("It should replace space by - for title label and trim it", func(t *testing.T) {
-		expected := []testTitleLabel{
-			{title: "foobar", titleLabel: "foobar"},
-			{title: "foo bar", titleLabel: "foo-bar"},
-			{title: "   foo bar title   ", titleLabel: "foo-bar-title"},
-			{title: "   foobar", titleLabel: "foobar"},
-			{title: "foobar     ", titleLabel: "foobar"},
-		}
+	t.Run("It should dedup on the canonical url instead of the title", func(t *testing.T) {
+		title, description := "foobar", "https://foo.dev/post?utm_source=rss"
 		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-			// On DTO creation, todoist is called to retrieve proper due date, returning empty to have today date
-			data, err := json.Marshal([]Task{})
-			if err != nil {
-				t.Fatal("can't marshal json for testserver answer")
+			if req.Method == http.MethodPost {
+				rw.Write([]byte(`{"id": "2"}`))
+				return
 			}
+			data, _ := json.Marshal([]Task{{Id: &id, Content: &title, Description: &description}})
 			rw.Write(data)
 		}))
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
-
-		for _, current := range expected {
-
-			got, err := todoist.createTodoDTO(current.title, current.title)
-
-			assertNoError(t, err)
-			if len(got.Labels) != 2 {
-				t.Fatalf("todo should have 2 labels")
-			}
-			assertEqualString(t, got.Labels[0], current.titleLabel)
+		for _, url := range []string{"https://foo.dev/post", "https://FOO.dev/post/#comments", "https://amp.foo.dev/post/?fbclid=1"} {
+			_, err := todoist.CreateTodoWithOptions("another title", url, TodoOptions{})
+			assertError(t, err, ErrAlreadyExist)
 		}
+		_, err := todoist.CreateTodoWithOptions(title, "https://bar.dev/post", TodoOptions{})
+		assertNoError(t, err)
+
+		found, err := todoist.FindTodo("https://foo.dev/post/")
+		assertNoError(t, err)
+		assertEqualString(t, *found.Id, id)
 	})
 
 	t.Run("It should return empty DTO on todoist error", func(t *testing.T) {
@@ -361,7 +360,7 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
-		got, err := todoist.createTodoDTO(title, title)
+		got, err := todoist.CreateTodoWithOptions(title, title, TodoOptions{})
 
 		if err == nil {
 			t.Fatal("didn't get any error but wanted one")
@@ -388,7 +387,12 @@ func TestTodoist(t *testing.T) {
 				if err != nil {
 					t.Fatal("can't unmarshal json for testserver request")
 				}
-				rw.Write([]byte("OK"))
+				todoInReq.Id = &id
+				data, err := json.Marshal(todoInReq)
+				if err != nil {
+					t.Fatal("can't marshal json for testserver answer")
+				}
+				rw.Write(data)
 			} else {
 				data, err := json.Marshal([]Task{})
 				if err != nil {
@@ -400,33 +404,25 @@ func TestTodoist(t *testing.T) {
 		defer server.Close()
 
 		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
-		err := todoist.CreateTodo(title, description)
+		created, err := todoist.CreateTodoWithOptions(title, description, TodoOptions{})
 
 		assertNoError(t, err)
+		assertEqualString(t, *created.Id, id)
 		assertEqualString(t, *todoInReq.ProjectId, id)
 		assertEqualString(t, *todoInReq.Content, title)
 		assertEqualString(t, *todoInReq.Description, description)
-		if len(todoInReq.Labels) != 2 {
-			t.Fatalf("todo should have 2 labels but todo %d", len(todoInReq.Labels))
+		if len(todoInReq.Labels) != 0 {
+			t.Fatalf("todo should not have labels but got %v", todoInReq.Labels)
 		}
-		assertEqualString(t, todoInReq.Labels[0], title)
-		assertEqualString(t, todoInReq.Labels[1], dueDateFormated)
+		assertEqualString(t, *todoInReq.DueDate, dueDateFormated)
 	})
 
 	t.Run("It should return error when an error happens in createTodoDTO", func(t *testing.T) {
 		title := "foobar"
-		dueDateFormated := time.Now().Format("2006-01-02")
 
 		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-			if strings.Contains(req.URL.RawQuery, dueDateFormated) {
-				http.Error(rw, "oups", http.StatusInternalServerError)
-			} else {
-				data, err := json.Marshal([]Task{})
-				if err != nil {
-					t.Fatal("can't marshal json for testserver answer")
-				}
-				rw.Write(data)
-			}
+			// the listing looks for duplicates and for a free day at once
+			http.Error(rw, "oups", http.StatusInternalServerError)
 		}))
 		defer server.Close()
 
@@ -439,6 +435,30 @@ func TestTodoist(t *testing.T) {
 		assertEqualString(t, err.Error(), fmt.Sprintf("%s: oups\n", ErrHttpRequestDefault))
 	})
 
+	t.Run("It should list the project once per creation", func(t *testing.T) {
+		listings := 0
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodPost {
+				rw.Write([]byte(`{"id": "2"}`))
+				return
+			}
+			listings++
+			rw.Write([]byte(`[]`))
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		_, err := todoist.CreateTodoWithOptions("foo", "https://foo.dev", TodoOptions{})
+		assertNoError(t, err)
+		_, _, err = todoist.CreateTodoGroup("digest", []TodoItem{{Title: "a", Description: "https://a.dev"}, {Title: "b", Description: "https://b.dev"}}, TodoOptions{})
+		assertNoError(t, err)
+		_, err = todoist.CreateTodos([]TodoItem{{Title: "a", Description: "https://a.dev"}, {Title: "b", Description: "https://b.dev"}}, TodoOptions{})
+		assertNoError(t, err)
+		if listings != 3 {
+			t.Fatalf("got %d listings for 3 creations, want 3", listings)
+		}
+	})
+
 	t.Run("It should return error when an error on POST task", func(t *testing.T) {
 		title := "foobar"
 
@@ -463,4 +483,381 @@ func TestTodoist(t *testing.T) {
 
 		assertEqualString(t, err.Error(), fmt.Sprintf("%s: oups\n", ErrHttpRequestDefault))
 	})
+
+	t.Run("It should find todo from its description", func(t *testing.T) {
+		otherId := "67890"
+		title := "foobar"
+		description := "https://foo.bar"
+		otherDescription := "https://bar.foo"
+		todos := []Task{
+			{Id: &otherId, Content: &title, Description: &otherDescription},
+			{Id: &id, Content: &title, Description: &description},
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			data, err := json.Marshal(todos)
+			if err != nil {
+				t.Fatal("can't marshal json for testserver answer")
+			}
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		got, err := todoist.FindTodo(description)
+
+		assertNoError(t, err)
+		assertEqualString(t, *got.Id, id)
+	})
+
+	t.Run("It should return error when no todo match description", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			data, err := json.Marshal([]Task{})
+			if err != nil {
+				t.Fatal("can't marshal json for testserver answer")
+			}
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		_, err := todoist.FindTodo("https://foo.bar")
+
+		assertError(t, err, ErrTodoNotFound)
+	})
+
+	t.Run("It should delete todo", func(t *testing.T) {
+		var method, path string
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			method = req.Method
+			path = req.URL.Path
+			rw.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		err := todoist.DeleteTodo(id)
+
+		assertNoError(t, err)
+		assertEqualString(t, method, http.MethodDelete)
+		assertEqualString(t, path, "/tasks/"+id)
+	})
+
+	t.Run("It should close todo", func(t *testing.T) {
+		var method, path string
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			method = req.Method
+			path = req.URL.Path
+			rw.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		err := todoist.CloseTodo(id)
+
+		assertNoError(t, err)
+		assertEqualString(t, method, http.MethodPost)
+		assertEqualString(t, path, "/tasks/"+id+"/close")
+	})
+
+	t.Run("It should tell a todo already removed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		assertError(t, todoist.DeleteTodo(id), ErrTodoNotFound)
+		assertError(t, todoist.CloseTodo(id), ErrTodoNotFound)
+	})
+
+	t.Run("It should return error when delete or close without init", func(t *testing.T) {
+		todoist := Todoist{}
+		assertError(t, todoist.DeleteTodo(id), ErrNotInitialized)
+		assertError(t, todoist.CloseTodo(id), ErrNotInitialized)
+	})
+
+	t.Run("It should apply priority and snooze options to DTO", func(t *testing.T) {
+		startDate := time.Now().AddDate(0, 0, 3)
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			data, err := json.Marshal([]Task{})
+			if err != nil {
+				t.Fatal("can't marshal json for testserver answer")
+			}
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		todos, err := todoist.openTodos(ctx)
+		assertNoError(t, err)
+		got, err := todoist.createTodoDTO(todos, "foo", "foo", TodoOptions{Priority: 4, StartDate: startDate})
+
+		assertNoError(t, err)
+		if got.Priority != 4 {
+			t.Fatalf("got priority %d, want 4", got.Priority)
+		}
+		assertEqualString(t, *got.DueDate, startDate.Format("2006-01-02"))
+	})
+
+	t.Run("It should move a group to the next day when it does not fit", func(t *testing.T) {
+		dateFormated := "1970-01-01"
+		todos := dueOn(3, dateFormated)
+		date, err := time.Parse("2006-01-02", dateFormated)
+		assertNoError(t, err)
+
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			data, err := json.Marshal(todos)
+			if err != nil {
+				t.Fatal("can't marshal json for testserver answer")
+			}
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX"}
+		dueDate, err := todoist.defineDueDateForSlots(ctx, date, 2)
+		assertNoError(t, err)
+		assertEqualString(t, dueDate, dateFormated)
+
+		dueDate, err = todoist.defineDueDateForSlots(ctx, date, 3)
+		assertNoError(t, err)
+		assertEqualString(t, dueDate, date.AddDate(0, 0, 1).Format("2006-01-02"))
+	})
+
+	groupServer := func(t *testing.T, existingUrl string, posted *[]Task) *httptest.Server {
+		count := 0
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodPost {
+				var todo Task
+				responseData, _ := io.ReadAll(req.Body)
+				err := json.Unmarshal(responseData, &todo)
+				if err != nil {
+					t.Fatal("can't unmarshal json for testserver request")
+				}
+				count++
+				todoId := fmt.Sprint(count)
+				todo.Id = &todoId
+				*posted = append(*posted, todo)
+				data, _ := json.Marshal(todo)
+				rw.Write(data)
+				return
+			}
+
+			todos := []Task{}
+			if existingUrl != "" {
+				todos = append(todos, Task{Description: &existingUrl})
+			}
+			data, _ := json.Marshal(todos)
+			rw.Write(data)
+		}))
+	}
+
+	t.Run("It should create a parent todo with one subtask per link", func(t *testing.T) {
+		var posted []Task
+		server := groupServer(t, "", &posted)
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		items := []TodoItem{{Title: "foo", Description: "https://foo.dev"}, {Title: "bar", Description: "https://bar.dev"}}
+		parent, children, err := todoist.CreateTodoGroup("digest", items, TodoOptions{})
+		dueDate := time.Now().Format("2006-01-02")
+
+		assertNoError(t, err)
+		assertEqualString(t, *parent.Content, "digest")
+		assertEqualString(t, *parent.DueDate, dueDate)
+		if len(parent.Labels) != 0 {
+			t.Fatalf("parent should not have labels but got %v", parent.Labels)
+		}
+		if len(children) != 2 {
+			t.Fatalf("got %d subtasks, want 2", len(children))
+		}
+		for i, child := range children {
+			assertEqualString(t, *child.ParentId, *parent.Id)
+			assertEqualString(t, *child.Description, items[i].Description)
+			assertEqualString(t, *child.DueDate, dueDate)
+			if len(child.Labels) != 0 {
+				t.Fatalf("subtask should not have labels but got %v", child.Labels)
+			}
+		}
+	})
+
+	t.Run("It should put the configured labels on the todos created", func(t *testing.T) {
+		var posted []Task
+		server := groupServer(t, "", &posted)
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id, Labels: []string{"news"}}
+		items := []TodoItem{{Title: "foo", Description: "https://foo.dev"}, {Title: "bar", Description: "https://bar.dev"}}
+		_, _, err := todoist.CreateTodoGroup("digest", items, TodoOptions{})
+		assertNoError(t, err)
+		_, err = todoist.CreateTodos([]TodoItem{{Title: "baz", Description: "https://baz.dev"}}, TodoOptions{})
+		assertNoError(t, err)
+		_, err = todoist.CreateTodoWithOptions("qux", "https://qux.dev", TodoOptions{})
+		assertNoError(t, err)
+
+		if len(posted) != 5 {
+			t.Fatalf("got %d todos posted, want 5", len(posted))
+		}
+		for _, todo := range posted {
+			if len(todo.Labels) != 1 || todo.Labels[0] != "news" {
+				t.Fatalf("todo %q should be labeled news but got %v", *todo.Content, todo.Labels)
+			}
+		}
+	})
+
+	t.Run("It should count every subtask as a slot when configured", func(t *testing.T) {
+		dateFormated := "1970-01-01"
+		parentId, childId := "1", "2"
+		todos := dueOn(1, dateFormated, dateFormated, dateFormated)
+		todos[0].Id = &parentId
+		todos[1].Id, todos[1].ParentId = &childId, &parentId
+		todos[2].ParentId = &parentId
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			data, _ := json.Marshal(todos)
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		perDay, err := todoist.todosPerDay(ctx)
+		assertNoError(t, err)
+		if perDay[dateFormated] != 1 {
+			t.Fatalf("a group should take a single slot, got %d", perDay[dateFormated])
+		}
+
+		todoist.SlotPerLink = true
+		perDay, err = todoist.todosPerDay(ctx)
+		assertNoError(t, err)
+		if perDay[dateFormated] != 2 {
+			t.Fatalf("every subtask should take a slot, got %d", perDay[dateFormated])
+		}
+	})
+
+	t.Run("It should skip links already existing in a group", func(t *testing.T) {
+		var posted []Task
+		server := groupServer(t, "https://foo.dev/", &posted)
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		items := []TodoItem{{Title: "foo", Description: "https://foo.dev"}, {Title: "bar", Description: "https://bar.dev"}}
+		_, children, err := todoist.CreateTodoGroup("digest", items, TodoOptions{})
+
+		assertNoError(t, err)
+		if len(children) != 1 {
+			t.Fatalf("got %d subtasks, want 1", len(children))
+		}
+		assertEqualString(t, *children[0].Content, "bar")
+
+		_, _, err = todoist.CreateTodoGroup("digest", items[:1], TodoOptions{})
+		assertError(t, err, ErrAlreadyExist)
+	})
+
+	t.Run("It should use configured capacity instead of defaults", func(t *testing.T) {
+		dateFormated := "1970-01-01"
+		date, err := time.Parse("2006-01-02", dateFormated)
+		assertNoError(t, err)
+		var todos []Task
+		for i := 0; i <= 3; i++ {
+			todos = append(todos, dueOn(2, date.AddDate(0, 0, i).Format("2006-01-02"))...)
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			data, err := json.Marshal(todos)
+			if err != nil {
+				t.Fatal("can't marshal json for testserver answer")
+			}
+			rw.Write(data)
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", MaxTodoPerDay: 2, MaxDaysToLookUp: 3}
+		dueDate, err := todoist.defineDueDate(ctx, date)
+
+		assertNoError(t, err)
+		assertEqualString(t, dueDate, date.AddDate(0, 0, 3).Format("2006-01-02"))
+	})
+
+	t.Run("It should follow the cursor of the api v1 listings", func(t *testing.T) {
+		pages := map[string]string{
+			"":       `{"results": [{"id": "1"}], "next_cursor": "second"}`,
+			"second": `{"results": [{"id": "2", "checked": true}], "next_cursor": null}`,
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			assertEqualString(t, req.URL.Query().Get("project_id"), id)
+			rw.Write([]byte(pages[req.URL.Query().Get("cursor")]))
+		}))
+		defer server.Close()
+
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id}
+		todos, err := todoist.projectTodos(ctx)
+
+		assertNoError(t, err)
+		if len(todos) != 2 || *todos[1].Id != "2" || taskToItem(todos[1]).Status != sink.StatusDone {
+			t.Fatalf("unexpected todos %+v", todos)
+		}
+	})
+
+	t.Run("It should select the api from its version", func(t *testing.T) {
+		baseUrl, err := baseUrlOf("")
+		assertNoError(t, err)
+		assertEqualString(t, baseUrl, BASE_URL)
+
+		baseUrl, err = baseUrlOf(API_VERSION_REST_V2)
+		assertNoError(t, err)
+		assertEqualString(t, baseUrl, REST_V2_BASE_URL)
+
+		todoist := Todoist{ApiVersion: "v3"}
+		err = todoist.Init(name)
+		if !errors.Is(err, ErrUnknownApiVersion) {
+			t.Fatalf("got %v, want %v", err, ErrUnknownApiVersion)
+		}
+	})
+
+	t.Run("It should send a failed POST again with the same request id", func(t *testing.T) {
+		var requestIds []string
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			requestIds = append(requestIds, req.Header.Get(REQUEST_ID_HEADER))
+			if len(requestIds) == 1 {
+				rw.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			rw.Write([]byte(`{"id": "1"}`))
+		}))
+		defer server.Close()
+
+		retry := NewRetry()
+		retry.BaseDelay = time.Millisecond
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, apiKey: "XXX", projectId: id, Retry: retry}
+		created, err := todoist.postTodo(ctx, Task{})
+
+		assertNoError(t, err)
+		assertEqualString(t, *created.Id, "1")
+		if len(requestIds) != 2 || requestIds[0] == "" || requestIds[0] != requestIds[1] {
+			t.Fatalf("unexpected request ids %q", requestIds)
+		}
+	})
+
+	t.Run("It should stop waiting for todoist when the context is done", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+		}))
+		defer server.Close()
+
+		deadline, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		todoist := Todoist{Client: server.Client(), baseUrl: server.URL, ApiKey: "XXX", Retry: NewRetry()}
+		err := todoist.InitContext(deadline, name)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		todoist.apiKey = "XXX"
+		err = todoist.DeleteTodoContext(cancelled, "1")
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, want %v", err, context.Canceled)
+		}
+	})
 }
@@ -16,27 +16,46 @@ type Project struct {
 	IsShared       bool    `json:"is_shared"`
 	IsFavorite     bool    `json:"is_favorite"`
 	IsInboxProject bool    `json:"is_inbox_project"`
+	InboxProject   bool    `json:"inbox_project"`
 	IsTeamInbox    bool    `json:"is_team_inbox"`
 	ViewStyle      *string `json:"view_style"`
 	Url            *string `json:"url"`
 }
 
+type Section struct {
+	Id        *string `json:"id"`
+	ProjectId *string `json:"project_id"`
+	Name      *string `json:"name"`
+	Order     int     `json:"order"`
+}
+
+type Label struct {
+	Id         *string `json:"id"`
+	Name       *string `json:"name"`
+	Color      *string `json:"color"`
+	Order      int     `json:"order"`
+	IsFavorite bool    `json:"is_favorite"`
+}
+
 type Task struct {
-	Id           *string   `json:"id"`
-	ProjectId    *string   `json:"project_id"`
-	SectionId    *string   `json:"section_id"`
-	Content      *string   `json:"content"`
-	Description  *string   `json:"description"`
-	IsCompleted  bool      `json:"is_completed"`
-	Labels       []string  `json:"labels"`
+	Id          *string `json:"id"`
+	ProjectId   *string `json:"project_id"`
+	SectionId   *string `json:"section_id"`
+	Content     *string `json:"content"`
+	Description *string `json:"description"`
+	IsCompleted bool    `json:"is_completed"`
+	// Checked replaces IsCompleted in the api v1.
+	Checked      bool      `json:"checked"`
+	Labels       []string  `json:"labels,omitempty"`
 	ParentId     *string   `json:"parent_id"`
 	Order        int       `json:"order"`
-	Priority     int       `json:"priority"`
+	Priority     int       `json:"priority,omitempty"`
 	Due          *Due      `json:"due"`
 	Deadline     *Deadline `json:"deadline"`
 	Url          *string   `json:"url"`
 	CommentCount int       `json:"comment_count"`
 	CreatedAt    *string   `json:"created_at"`
+	CompletedAt  *string   `json:"completed_at"`
 	CreatorId    *string   `json:"creator_id"`
 	AssigneeId   *string   `json:"assignee_id"`
 	AssignerId   *string   `json:"assigner_id"`
@@ -0,0 +1,309 @@
+package wallabag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/httpapi"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+const (
+	PAGE_SIZE = 100
+	// TOKEN_MARGIN is how long before its expiry a token is renewed.
+	TOKEN_MARGIN = time.Minute
+)
+
+var (
+	ErrHttpRequestDefault      = httpapi.NewError("error on wallabag api call", httpapi.ErrApiCall)
+	ErrHttpRequestUnauthorized = httpapi.NewError("unauthorized wallabag access", httpapi.ErrUnauthorized)
+)
+
+var apiErrors = httpapi.Errors{
+	Default:      ErrHttpRequestDefault,
+	Unauthorized: ErrHttpRequestUnauthorized,
+	ByStatus: map[int]error{
+		http.StatusForbidden: ErrHttpRequestUnauthorized,
+		http.StatusNotFound:  sink.ErrNotFound,
+	},
+}
+
+// tokenErrors are the errors of the token endpoint, answering a bad request
+// on invalid credentials.
+var tokenErrors = httpapi.Errors{
+	Default:      ErrHttpRequestDefault,
+	Unauthorized: ErrHttpRequestUnauthorized,
+	ByStatus:     map[int]error{http.StatusBadRequest: ErrHttpRequestUnauthorized},
+}
+
+var _ sink.TaskSink = &Wallabag{}
+var _ sink.Completer = &Wallabag{}
+
+type Tag struct {
+	Label string `json:"label"`
+}
+
+type Entry struct {
+	Id         int    `json:"id"`
+	Url        string `json:"url"`
+	Title      string `json:"title"`
+	IsArchived int    `json:"is_archived"`
+	Tags       []Tag  `json:"tags"`
+}
+
+type newEntry struct {
+	Url   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Tags  string `json:"tags,omitempty"`
+}
+
+type entryPage struct {
+	Page     int `json:"page"`
+	Pages    int `json:"pages"`
+	Embedded struct {
+		Items []Entry `json:"items"`
+	} `json:"_embedded"`
+}
+
+type token struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Wallabag saves news as unread entries of the wallabag instance at
+// BaseUrl, authenticating with the OAuth password grant of an api client.
+// The news are never scheduled so Reschedule is not supported.
+type Wallabag struct {
+	Client       *http.Client
+	BaseUrl      string
+	ClientId     string
+	ClientSecret string
+	Username     string
+	Password     string
+
+	mutex       sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func (w *Wallabag) client() *http.Client {
+	if w.Client == nil {
+		return http.DefaultClient
+	}
+	return w.Client
+}
+
+func (w *Wallabag) url(path string) string {
+	return strings.TrimSuffix(w.BaseUrl, "/") + path
+}
+
+// token returns a valid access token, asking for a new one when needed.
+func (w *Wallabag) token(ctx context.Context) (accessToken string, err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.accessToken != "" && time.Now().Add(TOKEN_MARGIN).Before(w.expiresAt) {
+		return w.accessToken, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"password"},
+		"client_id":     {w.ClientId},
+		"client_secret": {w.ClientSecret},
+		"username":      {w.Username},
+		"password":      {w.Password},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url("/oauth/v2/token"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := tokenErrors.Do(w.client(), request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	var answer token
+	err = json.NewDecoder(response.Body).Decode(&answer)
+	if err != nil {
+		return
+	}
+	w.accessToken = answer.AccessToken
+	w.expiresAt = time.Now().Add(time.Duration(answer.ExpiresIn) * time.Second)
+	return w.accessToken, nil
+}
+
+func (w *Wallabag) call(ctx context.Context, method, path string, body any, answer any) (err error) {
+	accessToken, err := w.token(ctx)
+	if err != nil {
+		return
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, w.url(path), reader)
+	if err != nil {
+		return
+	}
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := apiErrors.Do(w.client(), request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if answer == nil {
+		return
+	}
+	return json.NewDecoder(response.Body).Decode(answer)
+}
+
+func (e Entry) item() sink.Item {
+	item := sink.Item{
+		Id:     strconv.Itoa(e.Id),
+		Title:  e.Title,
+		Url:    e.Url,
+		Status: sink.StatusOpen,
+	}
+	for _, tag := range e.Tags {
+		item.Tags = append(item.Tags, tag.Label)
+	}
+	if e.IsArchived != 0 {
+		item.Status = sink.StatusDone
+	}
+	return item
+}
+
+// Init checks the credentials by asking for a token.
+func (w *Wallabag) Init(ctx context.Context) (err error) {
+	_, err = w.token(ctx)
+	return
+}
+
+func (w *Wallabag) find(ctx context.Context, entryUrl string) (entry Entry, err error) {
+	var exists struct {
+		Exists *int `json:"exists"`
+	}
+	err = w.call(ctx, http.MethodGet, "/api/entries/exists.json?return_id=1&url="+url.QueryEscape(entryUrl), nil, &exists)
+	if err != nil {
+		return
+	}
+	if exists.Exists == nil {
+		return entry, sink.ErrNotFound
+	}
+
+	err = w.call(ctx, http.MethodGet, fmt.Sprintf("/api/entries/%d.json", *exists.Exists), nil, &entry)
+	if err != nil {
+		return
+	}
+	if entry.IsArchived != 0 {
+		return entry, sink.ErrNotFound
+	}
+	return
+}
+
+func (w *Wallabag) create(ctx context.Context, item sink.Item) (created sink.Item, err error) {
+	_, err = w.find(ctx, item.Url)
+	if err == nil {
+		return created, sink.ErrAlreadyExist
+	}
+	if err != sink.ErrNotFound {
+		return
+	}
+
+	var entry Entry
+	err = w.call(ctx, http.MethodPost, "/api/entries.json", newEntry{
+		Url:   item.Url,
+		Title: item.Title,
+		Tags:  strings.Join(item.Tags, ","),
+	}, &entry)
+	if err != nil {
+		return
+	}
+	return entry.item(), nil
+}
+
+// Create saves item as an entry. A group has no entry of its own, only its
+// children are saved.
+func (w *Wallabag) Create(ctx context.Context, item sink.Item) (created sink.Item, err error) {
+	if len(item.Children) == 0 {
+		return w.create(ctx, item)
+	}
+
+	created = item
+	created.Children = nil
+	for _, child := range item.Children {
+		if len(child.Tags) == 0 {
+			child.Tags = item.Tags
+		}
+		entry, err := w.create(ctx, child)
+		if err == sink.ErrAlreadyExist {
+			continue
+		}
+		if err != nil {
+			return created, err
+		}
+		created.Children = append(created.Children, entry)
+	}
+	if len(created.Children) == 0 {
+		return created, sink.ErrAlreadyExist
+	}
+	return
+}
+
+func (w *Wallabag) FindByUrl(ctx context.Context, url string) (item sink.Item, err error) {
+	entry, err := w.find(ctx, url)
+	if err != nil {
+		return
+	}
+	return entry.item(), nil
+}
+
+func (w *Wallabag) Delete(ctx context.Context, id string) (err error) {
+	return w.call(ctx, http.MethodDelete, fmt.Sprintf("/api/entries/%s.json", url.PathEscape(id)), nil, nil)
+}
+
+// Complete archives the entry.
+func (w *Wallabag) Complete(ctx context.Context, id string) (err error) {
+	return w.call(ctx, http.MethodPatch, fmt.Sprintf("/api/entries/%s.json", url.PathEscape(id)), map[string]int{"archive": 1}, nil)
+}
+
+func (w *Wallabag) Reschedule(ctx context.Context, id string, dueDate string) (err error) {
+	return sink.ErrNotSupported
+}
+
+// List returns the entries not archived.
+func (w *Wallabag) List(ctx context.Context) (items []sink.Item, err error) {
+	for page := 1; ; page++ {
+		var answer entryPage
+		err = w.call(ctx, http.MethodGet, fmt.Sprintf("/api/entries.json?archive=0&perPage=%d&page=%d", PAGE_SIZE, page), nil, &answer)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range answer.Embedded.Items {
+			items = append(items, entry.item())
+		}
+		if page >= answer.Pages {
+			return
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package wallabag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+// fakeWallabag serves the token and entries endpoints of the wallabag api.
+type fakeWallabag struct {
+	mutex   sync.Mutex
+	tokens  int
+	nextId  int
+	entries map[int]*Entry
+}
+
+func (f *fakeWallabag) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if req.URL.Path == "/oauth/v2/token" {
+		req.ParseForm()
+		if req.PostForm.Get("grant_type") != "password" || req.PostForm.Get("client_id") != "id" || req.PostForm.Get("password") != "secret" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.tokens++
+		json.NewEncoder(rw).Encode(token{AccessToken: "token", ExpiresIn: 3600})
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer token" {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case req.URL.Path == "/api/entries/exists.json":
+		var exists *int
+		for id, entry := range f.entries {
+			if entry.Url == req.URL.Query().Get("url") {
+				exists = &id
+			}
+		}
+		json.NewEncoder(rw).Encode(map[string]*int{"exists": exists})
+	case req.URL.Path == "/api/entries.json" && req.Method == http.MethodPost:
+		var created newEntry
+		json.NewDecoder(req.Body).Decode(&created)
+		f.nextId++
+		entry := &Entry{Id: f.nextId, Url: created.Url, Title: created.Title}
+		for _, label := range strings.Split(created.Tags, ",") {
+			entry.Tags = append(entry.Tags, Tag{Label: label})
+		}
+		f.entries[entry.Id] = entry
+		json.NewEncoder(rw).Encode(entry)
+	case req.URL.Path == "/api/entries.json":
+		var page entryPage
+		for id := 1; id <= f.nextId; id++ {
+			if entry, ok := f.entries[id]; ok && entry.IsArchived == 0 {
+				page.Embedded.Items = append(page.Embedded.Items, *entry)
+			}
+		}
+		page.Page, page.Pages = 1, 1
+		json.NewEncoder(rw).Encode(page)
+	default:
+		var id int
+		fmt.Sscanf(req.URL.Path, "/api/entries/%d.json", &id)
+		entry, ok := f.entries[id]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch req.Method {
+		case http.MethodPatch:
+			var patch map[string]int
+			json.NewDecoder(req.Body).Decode(&patch)
+			entry.IsArchived = patch["archive"]
+		case http.MethodDelete:
+			delete(f.entries, id)
+		}
+		json.NewEncoder(rw).Encode(entry)
+	}
+}
+
+func TestWallabag(t *testing.T) {
+	ctx := context.Background()
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertError := func(t testing.TB, got, want error) {
+		t.Helper()
+		if !errors.Is(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	newWallabag := func(t testing.TB) (*Wallabag, *fakeWallabag) {
+		t.Helper()
+		fake := &fakeWallabag{entries: map[int]*Entry{}}
+		server := httptest.NewServer(fake)
+		t.Cleanup(server.Close)
+
+		wallabag := &Wallabag{Client: server.Client(), BaseUrl: server.URL, ClientId: "id", ClientSecret: "client-secret", Username: "me", Password: "secret"}
+		return wallabag, fake
+	}
+
+	t.Run("It should handle error on invalid credentials", func(t *testing.T) {
+		wallabag, _ := newWallabag(t)
+		wallabag.Password = "wrong"
+		assertError(t, wallabag.Init(ctx), ErrHttpRequestUnauthorized)
+	})
+
+	t.Run("It should save an entry with tags only once and reuse the token", func(t *testing.T) {
+		wallabag, fake := newWallabag(t)
+		created, err := wallabag.Create(ctx, sink.Item{Title: "Go", Url: "https://go.dev", Tags: []string{"👍", "golang"}})
+		assertNoError(t, err)
+		if created.Id != "1" || strings.Join(created.Tags, ",") != "👍,golang" {
+			t.Fatalf("unexpected entry %+v", created)
+		}
+
+		_, err = wallabag.Create(ctx, sink.Item{Title: "Go", Url: "https://go.dev"})
+		assertError(t, err, sink.ErrAlreadyExist)
+		if fake.tokens != 1 {
+			t.Fatalf("got %d tokens, want 1", fake.tokens)
+		}
+	})
+
+	t.Run("It should save every link of a group", func(t *testing.T) {
+		wallabag, fake := newWallabag(t)
+		parent, err := wallabag.Create(ctx, sink.Item{Title: "Digest", Children: []sink.Item{
+			{Title: "a", Url: "https://a.dev"},
+			{Title: "b", Url: "https://b.dev"},
+		}})
+		assertNoError(t, err)
+		if parent.Id != "" || len(parent.Children) != 2 || len(fake.entries) != 2 {
+			t.Fatalf("unexpected group %+v", parent)
+		}
+	})
+
+	t.Run("It should archive, delete and list entries", func(t *testing.T) {
+		wallabag, _ := newWallabag(t)
+		first, _ := wallabag.Create(ctx, sink.Item{Url: "https://a.dev"})
+		second, _ := wallabag.Create(ctx, sink.Item{Url: "https://b.dev"})
+		wallabag.Create(ctx, sink.Item{Url: "https://c.dev"})
+
+		assertNoError(t, wallabag.Complete(ctx, first.Id))
+		_, err := wallabag.FindByUrl(ctx, "https://a.dev")
+		assertError(t, err, sink.ErrNotFound)
+		assertNoError(t, wallabag.Delete(ctx, second.Id))
+		assertError(t, wallabag.Delete(ctx, second.Id), sink.ErrNotFound)
+		assertError(t, wallabag.Reschedule(ctx, first.Id, "2026-01-02"), sink.ErrNotSupported)
+
+		items, err := wallabag.List(ctx)
+		assertNoError(t, err)
+		if len(items) != 1 || items[0].Url != "https://c.dev" {
+			t.Fatalf("unexpected items %+v", items)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/jobs"
+)
+
+var ErrDeadLetterUsage = errors.New("usage: dead-letter list | retry <id>... | discard <id>...")
+
+// runDeadLetter lets an admin inspect the jobs that failed for good, send
+// them back to the pending ones or drop them.
+func runDeadLetter(journalPath string, args []string) (err error) {
+	if len(args) == 0 {
+		return ErrDeadLetterUsage
+	}
+
+	journal, err := jobs.Open(journalPath)
+	if err != nil {
+		return
+	}
+
+	switch args[0] {
+	case "list":
+		dead, err := journal.Dead()
+		if err != nil {
+			return err
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "ID\tKIND\tCHANNEL\tMESSAGE\tEMOJI\tSINK\tATTEMPTS\tCREATED\tLAST ERROR")
+		for _, job := range dead {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				job.Id, job.Kind, job.ChannelId, job.MessageId, job.EmojiName, job.Sink,
+				job.Attempts, job.CreatedAt.Format(time.RFC3339), job.LastError)
+		}
+		return writer.Flush()
+	case "retry", "discard":
+		if len(args) < 2 {
+			return ErrDeadLetterUsage
+		}
+		for _, id := range args[1:] {
+			if args[0] == "retry" {
+				err = journal.Retry(id)
+			} else {
+				err = journal.Discard(id)
+			}
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", args[0], id, err)
+			}
+		}
+		return
+	}
+	return ErrDeadLetterUsage
+}
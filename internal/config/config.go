@@ -0,0 +1,444 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	CONFIG_FILE            = "CONFIG_FILE"
+	DISCORD_TOKEN          = "DISCORD_TOKEN"
+	API_KEY                = "API_KEY"
+	CALDAV_PASSWORD        = "CALDAV_PASSWORD"
+	WALLABAG_CLIENT_SECRET = "WALLABAG_CLIENT_SECRET"
+	WALLABAG_PASSWORD      = "WALLABAG_PASSWORD"
+	LINKDING_TOKEN         = "LINKDING_TOKEN"
+	GITHUB_TOKEN           = "GITHUB_TOKEN"
+
+	BACKEND_TODOIST  = "todoist"
+	BACKEND_MEMORY   = "memory"
+	BACKEND_MARKDOWN = "markdown"
+	BACKEND_CALDAV   = "caldav"
+	BACKEND_WALLABAG = "wallabag"
+	BACKEND_LINKDING = "linkding"
+	BACKEND_GITHUB   = "github"
+)
+
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Schedule is the capacity of the backends scheduling news by day.
+type Schedule struct {
+	MaxTodoPerDay   int  `json:"max_todo_per_day"`
+	MaxDaysToLookUp int  `json:"max_days_to_look_up"`
+	SlotPerLink     bool `json:"slot_per_link"`
+}
+
+type Todoist struct {
+	ApiKey      string `json:"api_key"`
+	ProjectName string `json:"project_name"`
+	// ApiVersion is v1 for the unified api or rest/v2 for the former one.
+	ApiVersion string `json:"api_version"`
+	// Sync batches the calls through the sync api, only with the v1 api.
+	Sync bool `json:"sync"`
+	// SnapshotInterval is how many seconds the snapshot of the project is
+	// used before being read again, 0 disables it.
+	SnapshotInterval int `json:"snapshot_interval"`
+	// CompletedLookbackDays is how many days back the completed todos are
+	// checked for duplicates, 0 disables it.
+	CompletedLookbackDays int `json:"completed_lookback_days"`
+	// Bootstrap creates the project, the sections and the labels missing
+	// on start instead of failing.
+	Bootstrap bool     `json:"bootstrap"`
+	Sections  []string `json:"sections"`
+	Labels    []string `json:"labels"`
+	// Routes sends the news to a section of the project.
+	Routes []Route `json:"routes"`
+}
+
+// Route matches a news on its discord channel, the emoji of the reaction
+// and its domain, the fields left empty match anything.
+type Route struct {
+	Channel string `json:"channel,omitempty"`
+	Emoji   string `json:"emoji,omitempty"`
+	Domain  string `json:"domain,omitempty"`
+	Section string `json:"section"`
+}
+
+// Markdown is the vault backend, mode is note for one note per news or daily
+// for one reading list per day.
+type Markdown struct {
+	Dir  string `json:"dir"`
+	Mode string `json:"mode"`
+}
+
+// CalDAV is the VTODO backend, url is the one of the calendar collection.
+type CalDAV struct {
+	Url      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type Wallabag struct {
+	Url          string `json:"url"`
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+}
+
+type Linkding struct {
+	Url   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// GitHub is the issues backend, base url is the api of the server, the
+// public GitHub api when empty.
+type GitHub struct {
+	BaseUrl          string `json:"base_url"`
+	Token            string `json:"token"`
+	Repository       string `json:"repository"`
+	Label            string `json:"label"`
+	MaxIssuesPerWeek int    `json:"max_issues_per_week"`
+	MaxWeeksToLookUp int    `json:"max_weeks_to_look_up"`
+}
+
+type Config struct {
+	DiscordToken string `json:"discord_token"`
+	// Backend is a comma separated list of the backends news are saved to.
+	Backend         string   `json:"backend"`
+	Schedule        Schedule `json:"schedule"`
+	Todoist         Todoist  `json:"todoist"`
+	Markdown        Markdown `json:"markdown"`
+	CalDAV          CalDAV   `json:"caldav"`
+	Wallabag        Wallabag `json:"wallabag"`
+	Linkding        Linkding `json:"linkding"`
+	GitHub          GitHub   `json:"github"`
+	HttpTimeout     int      `json:"http_timeout"`
+	ReactionsFile   string   `json:"reactions_file"`
+	StorePath       string   `json:"store_path"`
+	JournalPath     string   `json:"journal_path"`
+	Workers         int      `json:"workers"`
+	QueueSize       int      `json:"queue_size"`
+	ShutdownTimeout int      `json:"shutdown_timeout"`
+	ReactionTimeout int      `json:"reaction_timeout"`
+	CloseOnRemove   bool     `json:"close_on_remove"`
+}
+
+func Default() Config {
+	return Config{
+		Backend: BACKEND_TODOIST,
+		Todoist: Todoist{
+			ProjectName:           "News",
+			ApiVersion:            "v1",
+			SnapshotInterval:      60,
+			CompletedLookbackDays: 90,
+		},
+		Schedule: Schedule{
+			MaxTodoPerDay:   5,
+			MaxDaysToLookUp: 30,
+		},
+		Markdown: Markdown{
+			Mode: "note",
+		},
+		HttpTimeout:     10,
+		StorePath:       "news-sorter.json",
+		JournalPath:     "news-sorter-jobs.json",
+		Workers:         4,
+		QueueSize:       100,
+		ShutdownTimeout: 30,
+		ReactionTimeout: 120,
+	}
+}
+
+// secretEnv is the list of settings that can be given through an env var
+// or through a file named by the same env var suffixed by _FILE.
+var secretEnv = map[string]func(*Config) *string{
+	DISCORD_TOKEN:          func(c *Config) *string { return &c.DiscordToken },
+	API_KEY:                func(c *Config) *string { return &c.Todoist.ApiKey },
+	CALDAV_PASSWORD:        func(c *Config) *string { return &c.CalDAV.Password },
+	WALLABAG_CLIENT_SECRET: func(c *Config) *string { return &c.Wallabag.ClientSecret },
+	WALLABAG_PASSWORD:      func(c *Config) *string { return &c.Wallabag.Password },
+	LINKDING_TOKEN:         func(c *Config) *string { return &c.Linkding.Token },
+	GITHUB_TOKEN:           func(c *Config) *string { return &c.GitHub.Token },
+}
+
+var stringEnv = map[string]func(*Config) *string{
+	"BACKEND":             func(c *Config) *string { return &c.Backend },
+	"PROJECT_NAME":        func(c *Config) *string { return &c.Todoist.ProjectName },
+	"TODOIST_API_VERSION": func(c *Config) *string { return &c.Todoist.ApiVersion },
+	"MARKDOWN_DIR":        func(c *Config) *string { return &c.Markdown.Dir },
+	"MARKDOWN_MODE":       func(c *Config) *string { return &c.Markdown.Mode },
+	"CALDAV_URL":          func(c *Config) *string { return &c.CalDAV.Url },
+	"CALDAV_USERNAME":     func(c *Config) *string { return &c.CalDAV.Username },
+	"WALLABAG_URL":        func(c *Config) *string { return &c.Wallabag.Url },
+	"WALLABAG_CLIENT_ID":  func(c *Config) *string { return &c.Wallabag.ClientId },
+	"WALLABAG_USERNAME":   func(c *Config) *string { return &c.Wallabag.Username },
+	"LINKDING_URL":        func(c *Config) *string { return &c.Linkding.Url },
+	"GITHUB_REPOSITORY":   func(c *Config) *string { return &c.GitHub.Repository },
+}
+
+var intEnv = map[string]func(*Config) *int{
+	"MAX_TODO_PER_DAY":                func(c *Config) *int { return &c.Schedule.MaxTodoPerDay },
+	"MAX_DAYS_TO_LOOK_UP":             func(c *Config) *int { return &c.Schedule.MaxDaysToLookUp },
+	"HTTP_TIMEOUT":                    func(c *Config) *int { return &c.HttpTimeout },
+	"REACTION_TIMEOUT":                func(c *Config) *int { return &c.ReactionTimeout },
+	"TODOIST_SNAPSHOT_INTERVAL":       func(c *Config) *int { return &c.Todoist.SnapshotInterval },
+	"TODOIST_COMPLETED_LOOKBACK_DAYS": func(c *Config) *int { return &c.Todoist.CompletedLookbackDays },
+}
+
+func newFlagSet(cfg *Config, configFile *string, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("aza-discord-news-sorter", flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	fs.StringVar(configFile, "config", *configFile, "path to the JSON configuration file")
+	fs.StringVar(&cfg.Backend, "backend", cfg.Backend, "comma separated backends the news are saved to (todoist, markdown, caldav, wallabag, linkding, github or memory)")
+	fs.StringVar(&cfg.Todoist.ProjectName, "project", cfg.Todoist.ProjectName, "todoist project receiving the news")
+	fs.StringVar(&cfg.Todoist.ApiVersion, "todoist-api-version", cfg.Todoist.ApiVersion, "todoist api version, v1 or rest/v2")
+	fs.BoolVar(&cfg.Todoist.Sync, "todoist-sync", cfg.Todoist.Sync, "read and write todoist through the sync api")
+	fs.BoolVar(&cfg.Todoist.Bootstrap, "todoist-bootstrap", cfg.Todoist.Bootstrap, "create the todoist project, sections and labels missing on start")
+	fs.IntVar(&cfg.Todoist.SnapshotInterval, "todoist-snapshot-interval", cfg.Todoist.SnapshotInterval, "seconds the snapshot of the todoist project is reused, 0 to disable it")
+	fs.IntVar(&cfg.Todoist.CompletedLookbackDays, "todoist-completed-lookback-days", cfg.Todoist.CompletedLookbackDays, "days the completed todoist todos are checked for duplicates, 0 to disable it")
+	fs.IntVar(&cfg.Schedule.MaxTodoPerDay, "max-todo-per-day", cfg.Schedule.MaxTodoPerDay, "number of todos scheduled per day")
+	fs.IntVar(&cfg.Schedule.MaxDaysToLookUp, "max-days-to-look-up", cfg.Schedule.MaxDaysToLookUp, "number of days looked up for a free slot")
+	fs.BoolVar(&cfg.Schedule.SlotPerLink, "slot-per-link", cfg.Schedule.SlotPerLink, "count every link of a multi-link message as a slot of the day")
+	fs.StringVar(&cfg.Markdown.Dir, "markdown-dir", cfg.Markdown.Dir, "directory of the markdown vault")
+	fs.StringVar(&cfg.Markdown.Mode, "markdown-mode", cfg.Markdown.Mode, "note for a note per news or daily for a reading list per day")
+	fs.StringVar(&cfg.CalDAV.Url, "caldav-url", cfg.CalDAV.Url, "url of the caldav calendar collection")
+	fs.StringVar(&cfg.CalDAV.Username, "caldav-username", cfg.CalDAV.Username, "caldav user name")
+	fs.StringVar(&cfg.Wallabag.Url, "wallabag-url", cfg.Wallabag.Url, "url of the wallabag instance")
+	fs.StringVar(&cfg.Linkding.Url, "linkding-url", cfg.Linkding.Url, "url of the linkding instance")
+	fs.StringVar(&cfg.GitHub.Repository, "github-repository", cfg.GitHub.Repository, "owner/name of the repository receiving the issues")
+	fs.IntVar(&cfg.HttpTimeout, "timeout", cfg.HttpTimeout, "http client timeout in seconds")
+	fs.StringVar(&cfg.ReactionsFile, "reactions", cfg.ReactionsFile, "path to the emoji to action mapping file")
+	fs.StringVar(&cfg.StorePath, "store", cfg.StorePath, "path to the message to task mapping store")
+	fs.StringVar(&cfg.JournalPath, "journal", cfg.JournalPath, "path to the journal of reactions to process")
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "number of reactions processed concurrently")
+	fs.IntVar(&cfg.QueueSize, "queue-size", cfg.QueueSize, "number of reactions waiting to be processed before refusing new ones")
+	fs.IntVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "seconds to wait for queued reactions on shutdown")
+	fs.IntVar(&cfg.ReactionTimeout, "reaction-timeout", cfg.ReactionTimeout, "seconds a reaction has to be processed before being retried later, 0 for no limit")
+	fs.BoolVar(&cfg.CloseOnRemove, "close-on-remove", cfg.CloseOnRemove, "close the todo instead of deleting it when the reaction is removed")
+	return fs
+}
+
+// Load builds the configuration from the defaults, then the JSON file, then
+// the env vars and finally the flags explicitly given in args. The
+// remaining positional args are returned as is. The result still has to be
+// checked with Validate.
+func Load(args []string) (cfg Config, rest []string, err error) {
+	cfg = Default()
+
+	// first pass only to find out the config file, flags are applied last
+	scratch := Default()
+	configFile := os.Getenv(CONFIG_FILE)
+	err = newFlagSet(&scratch, &configFile, os.Stderr).Parse(args)
+	if err != nil {
+		return
+	}
+
+	if configFile != "" {
+		err = loadFile(&cfg, configFile)
+		if err != nil {
+			return
+		}
+	}
+
+	err = loadEnv(&cfg)
+	if err != nil {
+		return
+	}
+
+	fs := newFlagSet(&cfg, &configFile, io.Discard)
+	err = fs.Parse(args)
+	if err != nil {
+		return
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+func loadFile(cfg *Config, path string) (err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(cfg)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, path, err)
+	}
+	return
+}
+
+func loadEnv(cfg *Config) (err error) {
+	for name, field := range secretEnv {
+		value, fromValue := os.LookupEnv(name)
+		file, fromFile := os.LookupEnv(name + "_FILE")
+		if fromValue && fromFile {
+			return fmt.Errorf("%w: %s and %s_FILE can't be both set", ErrInvalidConfig, name, name)
+		}
+		if fromFile {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("%w: %s_FILE: %w", ErrInvalidConfig, name, err)
+			}
+			value = strings.TrimSpace(string(data))
+		}
+		if fromValue || fromFile {
+			*field(cfg) = value
+		}
+	}
+
+	for name, field := range stringEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			*field(cfg) = value
+		}
+	}
+
+	for name, field := range intEnv {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be a number, got %q", ErrInvalidConfig, name, value)
+		}
+		*field(cfg) = number
+	}
+	return
+}
+
+// Backends returns the backends named by Backend.
+func (c Config) Backends() (backends []string) {
+	for _, backend := range strings.Split(c.Backend, ",") {
+		backend = strings.TrimSpace(backend)
+		if backend != "" {
+			backends = append(backends, backend)
+		}
+	}
+	return
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+	}
+
+	if c.DiscordToken == "" {
+		invalid("discord token is required (discord_token, %s or %s_FILE)", DISCORD_TOKEN, DISCORD_TOKEN)
+	}
+	backends := c.Backends()
+	if len(backends) == 0 {
+		invalid("at least one backend is required")
+	}
+	seen := map[string]bool{}
+	for _, backend := range backends {
+		if seen[backend] {
+			invalid("backend %q is given more than once", backend)
+		}
+		seen[backend] = true
+		c.validateBackend(backend, invalid)
+	}
+	if c.Schedule.MaxTodoPerDay < 1 {
+		invalid("max todo per day must be at least 1, got %d", c.Schedule.MaxTodoPerDay)
+	}
+	if c.Schedule.MaxDaysToLookUp < 1 {
+		invalid("max days to look up must be at least 1, got %d", c.Schedule.MaxDaysToLookUp)
+	}
+	if c.HttpTimeout < 1 {
+		invalid("http timeout must be at least 1 second, got %d", c.HttpTimeout)
+	}
+	if c.Workers < 1 {
+		invalid("workers must be at least 1, got %d", c.Workers)
+	}
+	if c.QueueSize < 1 {
+		invalid("queue size must be at least 1, got %d", c.QueueSize)
+	}
+	if c.ShutdownTimeout < 0 {
+		invalid("shutdown timeout can't be negative, got %d", c.ShutdownTimeout)
+	}
+	if c.ReactionTimeout < 0 {
+		invalid("reaction timeout can't be negative, got %d", c.ReactionTimeout)
+	}
+	if c.JournalPath == "" {
+		invalid("journal path can't be empty")
+	}
+	return errors.Join(errs...)
+}
+
+func (c Config) validateBackend(backend string, invalid func(format string, args ...any)) {
+	switch backend {
+	case BACKEND_TODOIST:
+		if c.Todoist.ApiKey == "" {
+			invalid("todoist api key is required (todoist.api_key, %s or %s_FILE)", API_KEY, API_KEY)
+		}
+		if c.Todoist.ProjectName == "" {
+			invalid("todoist project name can't be empty")
+		}
+		if c.Todoist.ApiVersion != "v1" && c.Todoist.ApiVersion != "rest/v2" {
+			invalid("todoist api version must be v1 or rest/v2, got %q", c.Todoist.ApiVersion)
+		}
+		if c.Todoist.Sync && c.Todoist.ApiVersion != "v1" {
+			invalid("todoist sync needs the v1 api")
+		}
+		if c.Todoist.SnapshotInterval < 0 {
+			invalid("todoist snapshot interval can't be negative, got %d", c.Todoist.SnapshotInterval)
+		}
+		if c.Todoist.CompletedLookbackDays < 0 {
+			invalid("todoist completed lookback can't be negative, got %d", c.Todoist.CompletedLookbackDays)
+		}
+		for i, route := range c.Todoist.Routes {
+			if route.Section == "" {
+				invalid("todoist route %d has no section", i+1)
+			}
+			if route.Channel == "" && route.Emoji == "" && route.Domain == "" {
+				invalid("todoist route %d to %q needs a channel, an emoji or a domain", i+1, route.Section)
+			}
+		}
+	case BACKEND_MARKDOWN:
+		if c.Markdown.Dir == "" {
+			invalid("markdown dir is required (markdown.dir or MARKDOWN_DIR)")
+		}
+		if c.Markdown.Mode != "note" && c.Markdown.Mode != "daily" {
+			invalid("markdown mode must be note or daily, got %q", c.Markdown.Mode)
+		}
+	case BACKEND_CALDAV:
+		if c.CalDAV.Url == "" {
+			invalid("caldav url is required (caldav.url or CALDAV_URL)")
+		}
+	case BACKEND_WALLABAG:
+		if c.Wallabag.Url == "" || c.Wallabag.ClientId == "" || c.Wallabag.ClientSecret == "" {
+			invalid("wallabag url, client id and client secret are required")
+		}
+		if c.Wallabag.Username == "" || c.Wallabag.Password == "" {
+			invalid("wallabag username and password are required")
+		}
+	case BACKEND_LINKDING:
+		if c.Linkding.Url == "" {
+			invalid("linkding url is required (linkding.url or LINKDING_URL)")
+		}
+		if c.Linkding.Token == "" {
+			invalid("linkding token is required (linkding.token, %s or %s_FILE)", LINKDING_TOKEN, LINKDING_TOKEN)
+		}
+	case BACKEND_GITHUB:
+		if owner, name, _ := strings.Cut(c.GitHub.Repository, "/"); owner == "" || name == "" {
+			invalid("github repository must be owner/name, got %q", c.GitHub.Repository)
+		}
+		if c.GitHub.Token == "" {
+			invalid("github token is required (github.token, %s or %s_FILE)", GITHUB_TOKEN, GITHUB_TOKEN)
+		}
+	case BACKEND_MEMORY:
+	default:
+		invalid("unknown backend %q", backend)
+	}
+}
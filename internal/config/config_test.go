@@ -0,0 +1,226 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertEqualString := func(t testing.TB, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	assertEqualInt := func(t testing.TB, got, want int) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+	}
+
+	writeFile := func(t testing.TB, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatal("can't write config file")
+		}
+		return path
+	}
+
+	t.Run("It should use defaults when nothing is given", func(t *testing.T) {
+		cfg, rest, err := Load(nil)
+
+		assertNoError(t, err)
+		assertEqualString(t, cfg.Todoist.ProjectName, "News")
+		assertEqualInt(t, cfg.Schedule.MaxTodoPerDay, 5)
+		assertEqualInt(t, cfg.Schedule.MaxDaysToLookUp, 30)
+		assertEqualInt(t, cfg.Todoist.CompletedLookbackDays, 90)
+		assertEqualInt(t, len(rest), 0)
+	})
+
+	t.Run("It should apply file, then env, then flags", func(t *testing.T) {
+		path := writeFile(t, `{
+			"discord_token": "file-token",
+			"todoist": {"api_key": "file-key", "project_name": "FileNews"},
+			"schedule": {"max_todo_per_day": 3},
+			"workers": 2
+		}`)
+		t.Setenv(CONFIG_FILE, path)
+		t.Setenv("PROJECT_NAME", "EnvNews")
+		t.Setenv("MAX_TODO_PER_DAY", "7")
+
+		cfg, rest, err := Load([]string{"-max-todo-per-day", "9", "dead-letter", "list"})
+
+		assertNoError(t, err)
+		assertEqualString(t, cfg.DiscordToken, "file-token")
+		assertEqualString(t, cfg.Todoist.ApiKey, "file-key")
+		assertEqualString(t, cfg.Todoist.ProjectName, "EnvNews")
+		assertEqualInt(t, cfg.Schedule.MaxTodoPerDay, 9)
+		assertEqualInt(t, cfg.Workers, 2)
+		assertEqualString(t, strings.Join(rest, " "), "dead-letter list")
+	})
+
+	t.Run("It should not let flag defaults override the file", func(t *testing.T) {
+		path := writeFile(t, `{"workers": 8}`)
+
+		cfg, _, err := Load([]string{"-config", path, "-queue-size", "10"})
+
+		assertNoError(t, err)
+		assertEqualInt(t, cfg.Workers, 8)
+		assertEqualInt(t, cfg.QueueSize, 10)
+	})
+
+	t.Run("It should read secrets from _FILE env vars", func(t *testing.T) {
+		secret := filepath.Join(t.TempDir(), "token")
+		err := os.WriteFile(secret, []byte("file-secret\n"), 0o600)
+		if err != nil {
+			t.Fatal("can't write secret file")
+		}
+		t.Setenv(DISCORD_TOKEN+"_FILE", secret)
+		t.Setenv(API_KEY, "env-key")
+
+		cfg, _, err := Load(nil)
+
+		assertNoError(t, err)
+		assertEqualString(t, cfg.DiscordToken, "file-secret")
+		assertEqualString(t, cfg.Todoist.ApiKey, "env-key")
+	})
+
+	t.Run("It should reject a secret given twice", func(t *testing.T) {
+		t.Setenv(API_KEY, "env-key")
+		t.Setenv(API_KEY+"_FILE", "/nowhere")
+
+		_, _, err := Load(nil)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("got %v, want %v", err, ErrInvalidConfig)
+		}
+	})
+
+	t.Run("It should reject unknown fields and invalid numbers", func(t *testing.T) {
+		path := writeFile(t, `{"unknown": true}`)
+		_, _, err := Load([]string{"-config", path})
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("got %v, want %v", err, ErrInvalidConfig)
+		}
+
+		t.Setenv("HTTP_TIMEOUT", "ten")
+		_, _, err = Load(nil)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("got %v, want %v", err, ErrInvalidConfig)
+		}
+	})
+
+	t.Run("It should report every invalid setting", func(t *testing.T) {
+		cfg := Default()
+		cfg.Workers = 0
+
+		err := cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("got %v, want %v", err, ErrInvalidConfig)
+		}
+		for _, want := range []string{"discord token", "api key", "workers"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Fatalf("error %q should mention %q", err, want)
+			}
+		}
+
+		cfg.DiscordToken = "token"
+		cfg.Todoist.ApiKey = "key"
+		cfg.Workers = 1
+		assertNoError(t, cfg.Validate())
+
+		cfg.Todoist.Routes = []Route{{Section: "Go"}, {Channel: "golang"}}
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "route 1 to \"Go\"") || !strings.Contains(err.Error(), "route 2 has no section") {
+			t.Fatalf("got %v, want route errors", err)
+		}
+		cfg.Todoist.Routes = nil
+
+		cfg.Todoist.CompletedLookbackDays = -1
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "completed lookback") {
+			t.Fatalf("got %v, want completed lookback error", err)
+		}
+		cfg.Todoist.CompletedLookbackDays = 0
+
+		cfg.Todoist.ApiVersion = "v9"
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "api version") {
+			t.Fatalf("got %v, want api version error", err)
+		}
+	})
+
+	t.Run("It should only require the api key for the todoist backend", func(t *testing.T) {
+		cfg, _, err := Load([]string{"-backend", BACKEND_MEMORY})
+		assertNoError(t, err)
+		cfg.DiscordToken = "token"
+		assertNoError(t, cfg.Validate())
+
+		cfg.Backend = BACKEND_MARKDOWN
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "markdown dir") {
+			t.Fatalf("got %v, want markdown dir error", err)
+		}
+		cfg.Markdown.Dir = "news"
+		assertNoError(t, cfg.Validate())
+
+		cfg.Backend = BACKEND_CALDAV
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "caldav url") {
+			t.Fatalf("got %v, want caldav url error", err)
+		}
+
+		cfg.Backend = BACKEND_LINKDING
+		cfg.Linkding.Url = "https://links.example.com"
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "linkding token") {
+			t.Fatalf("got %v, want linkding token error", err)
+		}
+
+		cfg.Backend = BACKEND_GITHUB
+		cfg.GitHub = GitHub{Repository: "news", Token: "token"}
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "owner/name") {
+			t.Fatalf("got %v, want github repository error", err)
+		}
+
+		cfg.Backend = "unknown"
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "unknown backend") {
+			t.Fatalf("got %v, want unknown backend error", err)
+		}
+	})
+
+	t.Run("It should validate every backend of a list", func(t *testing.T) {
+		cfg, _, err := Load([]string{"-backend", "memory, markdown"})
+		assertNoError(t, err)
+		cfg.DiscordToken = "token"
+		assertEqualString(t, strings.Join(cfg.Backends(), "|"), "memory|markdown")
+
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "markdown dir") {
+			t.Fatalf("got %v, want markdown dir error", err)
+		}
+		cfg.Markdown.Dir = "news"
+		assertNoError(t, cfg.Validate())
+
+		cfg.Backend = "memory,memory"
+		err = cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "more than once") {
+			t.Fatalf("got %v, want duplicated backend error", err)
+		}
+	})
+}
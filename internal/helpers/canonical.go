@@ -0,0 +1,195 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/idna"
+)
+
+// trackingParams are the query params only telling where a link was shared
+// or clicked, the ones prefixed by utm_ are dropped as well.
+var trackingParams = []string{
+	"fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid",
+	"mc_cid", "mc_eid", "igshid", "_hsenc", "_hsmi", "mkt_tok",
+	"ref", "ref_src", "ref_url", "si",
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || slices.Contains(trackingParams, name)
+}
+
+// ampCacheOriginal returns the link of the page served by an AMP cache, its
+// path being the original host and path, prefixed by s/ for https.
+func ampCacheOriginal(host, path string) (original string, ok bool) {
+	switch {
+	case strings.HasSuffix(host, ".cdn.ampproject.org"):
+		original, ok = strings.CutPrefix(path, "/c/")
+	case host == "www.google.com":
+		original, ok = strings.CutPrefix(path, "/amp/")
+	}
+	if !ok {
+		return
+	}
+	if secure, found := strings.CutPrefix(original, "s/"); found {
+		return "https://" + secure, true
+	}
+	return "http://" + original, true
+}
+
+// canonicalHost drops the mobile and AMP subdomains of host.
+func canonicalHost(host string) string {
+	labels := strings.Split(host, ".")
+	kept := make([]string, 0, len(labels))
+	for i, label := range labels {
+		if i < len(labels)-2 && (label == "m" || label == "mobile" || label == "amp") {
+			continue
+		}
+		kept = append(kept, label)
+	}
+	return strings.Join(kept, ".")
+}
+
+// canonicalPath drops the trailing slash of path, and its AMP version when
+// amp tells the link is known to be an AMP page. An /amp segment alone is
+// too common in paths to be taken for one.
+func canonicalPath(path string, amp bool) string {
+	path = strings.TrimSuffix(path, "/")
+	if !amp {
+		return path
+	}
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/amp"), ".amp")
+	if after, found := strings.CutPrefix(path, "/amp/"); found {
+		path = "/" + after
+	}
+	return path
+}
+
+// Canonicalize normalizes a link without fetching it: lowercase punycode
+// host without its mobile or AMP subdomain, no default port, tracking
+// params, fragment or trailing slash. The AMP version of the path is only
+// dropped for the links served by an AMP cache, GetPage relies on the
+// rel=canonical link of the page for the others.
+func Canonicalize(rawUrl string) (canonical string, err error) {
+	return canonicalize(rawUrl, false)
+}
+
+func canonicalize(rawUrl string, amp bool) (canonical string, err error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+
+	host, err := idna.Lookup.ToASCII(parsed.Hostname())
+	if err != nil {
+		host = strings.ToLower(parsed.Hostname())
+	}
+	if original, ok := ampCacheOriginal(host, parsed.Path); ok {
+		if parsed.RawQuery != "" {
+			original += "?" + parsed.RawQuery
+		}
+		return canonicalize(original, true)
+	}
+
+	port := parsed.Port()
+	parsed.Host = canonicalHost(host)
+	if port != "" && !(parsed.Scheme == "http" && port == "80") && !(parsed.Scheme == "https" && port == "443") {
+		parsed.Host += ":" + port
+	}
+	parsed.Path = canonicalPath(parsed.Path, amp)
+	if parsed.RawPath != "" {
+		parsed.RawPath = canonicalPath(parsed.RawPath, amp)
+	}
+
+	query := parsed.Query()
+	for name := range query {
+		if isTrackingParam(name) {
+			query.Del(name)
+		}
+	}
+	if query.Has("amp") || query.Get("outputType") == "amp" {
+		query.Del("amp")
+		query.Del("outputType")
+	}
+	parsed.RawQuery = query.Encode()
+	parsed.Fragment, parsed.RawFragment = "", ""
+	return parsed.String(), nil
+}
+
+// Page is what is read from the page of a link, Url being its canonical
+// link.
+type Page struct {
+	Title string
+	Url   string
+}
+
+// GetPage fetches the page of rawUrl with client, http.DefaultClient when
+// nil, following the redirects of short links, and reads its title and its
+// rel=canonical link.
+func GetPage(ctx context.Context, client *http.Client, rawUrl string) (page Page, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	finalUrl := resp.Request.URL
+	page.Url = finalUrl.String()
+	z := html.NewTokenizer(resp.Body)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		t := z.Token()
+		if t.Type == html.EndTagToken && t.Data == "head" || t.Type == html.StartTagToken && t.Data == "body" {
+			break
+		}
+
+		if t.Type == html.StartTagToken && t.Data == "title" && page.Title == "" {
+			if z.Next() == html.TextToken {
+				page.Title = strings.TrimSpace(z.Token().Data)
+			}
+		}
+		if (t.Type == html.StartTagToken || t.Type == html.SelfClosingTagToken) && t.Data == "link" {
+			if href := canonicalHref(t); href != "" {
+				if canonical, err := finalUrl.Parse(href); err == nil && (canonical.Scheme == "http" || canonical.Scheme == "https") {
+					page.Url = canonical.String()
+				}
+			}
+		}
+	}
+
+	page.Url, err = Canonicalize(page.Url)
+	return
+}
+
+func canonicalHref(link html.Token) (href string) {
+	isCanonical := false
+	for _, attribute := range link.Attr {
+		switch attribute.Key {
+		case "rel":
+			isCanonical = slices.Contains(strings.Fields(strings.ToLower(attribute.Val)), "canonical")
+		case "href":
+			href = strings.TrimSpace(attribute.Val)
+		}
+	}
+	if !isCanonical {
+		return ""
+	}
+	return href
+}
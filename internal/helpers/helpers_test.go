@@ -1,6 +1,12 @@
 package helpers
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
 
 func TestGetTitleFromUrl(t *testing.T) {
 	title, err := GetTitleFromUrl("https://go.dev")
@@ -22,3 +28,122 @@ func TestGetTitleFromUrl_Error(t *testing.T) {
 		t.Fatal("did not got an error but wanted one")
 	}
 }
+
+func TestExtractUrls(t *testing.T) {
+	assertUrls := func(t testing.TB, got, want []string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		embeds  []string
+		want    []string
+	}{
+		{name: "plain", content: "https://go.dev", want: []string{"https://go.dev"}},
+		{name: "in a sentence", content: "check this https://go.dev/blog, really.", want: []string{"https://go.dev/blog"}},
+		{name: "suppressed embed", content: "<https://go.dev/doc>", want: []string{"https://go.dev/doc"}},
+		{name: "spoiler", content: "||https://go.dev/spoiler||", want: []string{"https://go.dev/spoiler"}},
+		{name: "markdown", content: "[Go](https://go.dev/md) and [wiki](https://en.wikipedia.org/wiki/Go_(language))", want: []string{"https://go.dev/md", "https://en.wikipedia.org/wiki/Go_(language)"}},
+		{name: "several links", content: "https://a.dev https://b.dev https://a.dev", want: []string{"https://a.dev", "https://b.dev"}},
+		{name: "embeds", content: "new post https://a.dev", embeds: []string{"https://b.dev", "https://a.dev", ""}, want: []string{"https://a.dev", "https://b.dev"}},
+		{name: "no link", content: "foobar", want: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertUrls(t, ExtractUrls(test.content, test.embeds...), test.want)
+		})
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "already canonical", url: "https://go.dev/blog/go1.23", want: "https://go.dev/blog/go1.23"},
+		{name: "tracking params", url: "https://go.dev/blog?utm_source=rss&UTM_Medium=feed&fbclid=abc&page=2", want: "https://go.dev/blog?page=2"},
+		{name: "host case and default port", url: "HTTPS://Go.Dev:443/Blog", want: "https://go.dev/Blog"},
+		{name: "other port kept", url: "http://localhost:8080/", want: "http://localhost:8080"},
+		{name: "trailing slash and fragment", url: "https://go.dev/blog/#comments", want: "https://go.dev/blog"},
+		{name: "idn", url: "https://Bücher.example/livre", want: "https://xn--bcher-kva.example/livre"},
+		{name: "mobile subdomain", url: "https://en.m.wikipedia.org/wiki/Go", want: "https://en.wikipedia.org/wiki/Go"},
+		{name: "amp subdomain", url: "https://amp.example.com/news/article/", want: "https://example.com/news/article"},
+		{name: "amp path kept outside of a cache", url: "https://github.com/foo/bar/tree/main/amp", want: "https://github.com/foo/bar/tree/main/amp"},
+		{name: "amp prefix kept outside of a cache", url: "https://example.com/amp/article", want: "https://example.com/amp/article"},
+		{name: "amp extension kept outside of a cache", url: "https://example.com/article.amp", want: "https://example.com/article.amp"},
+		{name: "amp param", url: "https://example.com/article?amp&id=1", want: "https://example.com/article?id=1"},
+		{name: "amp cache", url: "https://example-com.cdn.ampproject.org/c/s/example.com/article.amp?utm_campaign=x", want: "https://example.com/article"},
+		{name: "google amp", url: "https://www.google.com/amp/s/www.example.com/amp/article", want: "https://www.example.com/article"},
+		{name: "two labels host kept", url: "https://m.dev/", want: "https://m.dev"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Canonicalize(test.url)
+			if err != nil {
+				t.Fatalf("got an error but didn't want one: %q", err)
+			}
+			if got != test.want {
+				t.Fatalf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetPage(t *testing.T) {
+	articles := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/amp/post":
+			fmt.Fprint(rw, `<html><head><title>Post</title><link rel="amphtml canonical" href="/post/?utm_source=amp"></head></html>`)
+		default:
+			fmt.Fprintf(rw, "<html><head><title>Title of %s</title></head><body><link rel=canonical href=/elsewhere></body></html>", req.URL.Path)
+		}
+	}))
+	defer articles.Close()
+	shortener := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		http.Redirect(rw, req, articles.URL+"/amp/post?utm_medium=social", http.StatusMovedPermanently)
+	}))
+	defer shortener.Close()
+
+	t.Run("It should follow the short link and the canonical link", func(t *testing.T) {
+		page, err := GetPage(context.Background(), nil, shortener.URL+"/xyz")
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+		if page.Title != "Post" || page.Url != articles.URL+"/post" {
+			t.Fatalf("got %+v, want the title and the canonical url of the post", page)
+		}
+	})
+
+	t.Run("It should only read the canonical link of the head", func(t *testing.T) {
+		page, err := GetPage(context.Background(), nil, articles.URL+"/article/?ref=home")
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+		if page.Title != "Title of /article/" || page.Url != articles.URL+"/article" {
+			t.Fatalf("got %+v", page)
+		}
+	})
+
+	t.Run("It should fetch the page with the given client", func(t *testing.T) {
+		noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		page, err := GetPage(context.Background(), noRedirect, shortener.URL+"/xyz")
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+		if page.Url != shortener.URL+"/xyz" {
+			t.Fatalf("got %+v, want the short link as the client doesn't follow redirects", page)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+)
+
+var urlRegex = regexp.MustCompile("https?://[^\\s<>|\\[\\]\"'`]+")
+
+// trimUrl drops the trailing punctuation that belongs to the sentence and
+// the closing parenthesis of a markdown link, while keeping balanced
+// parenthesis that are part of the url itself.
+func trimUrl(url string) string {
+	for {
+		trimmed := strings.TrimRight(url, ".,;:!?*_~")
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+			trimmed = strings.TrimSuffix(trimmed, ")")
+		}
+		if trimmed == url {
+			return url
+		}
+		url = trimmed
+	}
+}
+
+// ExtractUrls returns every distinct link found in a Discord message content
+// (plain, <suppressed>, ||spoiler|| or [markdown](links)) followed by the
+// embeds ones, in order of appearance.
+func ExtractUrls(content string, embedUrls ...string) (urls []string) {
+	seen := map[string]bool{}
+	add := func(url string) {
+		if url == "" || seen[url] {
+			return
+		}
+		seen[url] = true
+		urls = append(urls, url)
+	}
+
+	for _, match := range urlRegex.FindAllString(content, -1) {
+		add(trimUrl(match))
+	}
+	for _, url := range embedUrls {
+		add(strings.TrimSpace(url))
+	}
+	return
+}
@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+var (
+	ErrUnauthorized = sink.NewPermanentError("unauthorized api access")
+	ErrApiCall      = errors.New("error on api call")
+	ErrRateLimited  = errors.New("api rate limit reached")
+	ErrUnavailable  = errors.New("api unavailable")
+)
+
+// apiError is the error of a given api that is also matched by errors.Is
+// against its kind, ErrUnauthorized or ErrApiCall, and what its kind matches.
+type apiError struct {
+	message string
+	kind    error
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func (e *apiError) Is(target error) bool {
+	return target == e.kind || errors.Is(e.kind, target)
+}
+
+// NewError returns an error with message of the same kind as kind.
+func NewError(message string, kind error) error {
+	return &apiError{message: message, kind: kind}
+}
+
+// StatusError is the error of an answer without an error of its own, it is
+// matched by errors.Is against the Default error of the api and against
+// ErrRateLimited on a 429 or ErrUnavailable on a 5xx.
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the delay asked by the Retry-After header, if any.
+	RetryAfter time.Duration
+
+	err  error
+	body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.err, e.body)
+}
+
+func (e *StatusError) Unwrap() []error {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return []error{e.err, ErrRateLimited}
+	case e.StatusCode >= 500:
+		return []error{e.err, ErrUnavailable}
+	}
+	return []error{e.err}
+}
+
+// retryAfter reads the Retry-After header, given in seconds or as a date.
+func retryAfter(header string) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// Errors are the errors an api answers are turned into.
+type Errors struct {
+	Default      error
+	Unauthorized error
+	// ByStatus maps some other statuses to an error of their own.
+	ByStatus map[int]error
+}
+
+// Verify returns nil for the successful answers, Unauthorized on a 401 and
+// a StatusError wrapping Default and the body of the answer otherwise.
+func (e Errors) Verify(response *http.Response) (err error) {
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+	if err, ok := e.ByStatus[response.StatusCode]; ok {
+		return err
+	}
+	if response.StatusCode == http.StatusUnauthorized {
+		return e.Unauthorized
+	}
+	responseData, _ := io.ReadAll(response.Body)
+	return &StatusError{
+		StatusCode: response.StatusCode,
+		RetryAfter: retryAfter(response.Header.Get("Retry-After")),
+		err:        e.Default,
+		body:       string(responseData),
+	}
+}
+
+// Do sends request and verifies the answer, the body is already closed when
+// an error is returned.
+func (e Errors) Do(client *http.Client, request *http.Request) (response *http.Response, err error) {
+	response, err = client.Do(request)
+	if err != nil {
+		return
+	}
+
+	err = e.Verify(response)
+	if err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	errDefault := NewError("error on foo api call", ErrApiCall)
+	errUnauthorized := NewError("unauthorized foo access", ErrUnauthorized)
+	errGone := errors.New("gone")
+	apiErrors := Errors{Default: errDefault, Unauthorized: errUnauthorized, ByStatus: map[int]error{http.StatusGone: errGone}}
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(status)
+		rw.Write([]byte("oups"))
+	}))
+	defer server.Close()
+
+	do := func() error {
+		request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		response, err := apiErrors.Do(server.Client(), request)
+		if err == nil {
+			response.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("It should accept every successful status", func(t *testing.T) {
+		for _, status = range []int{http.StatusOK, http.StatusCreated, http.StatusNoContent, http.StatusMultiStatus} {
+			if err := do(); err != nil {
+				t.Fatalf("got an error for %d but didn't want one: %q", status, err)
+			}
+		}
+	})
+
+	t.Run("It should return the errors of the api", func(t *testing.T) {
+		status = http.StatusUnauthorized
+		err := do()
+		if err != errUnauthorized || !errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("got %v, want %v", err, errUnauthorized)
+		}
+
+		status = http.StatusGone
+		if err := do(); err != errGone {
+			t.Fatalf("got %v, want %v", err, errGone)
+		}
+
+		status = http.StatusInternalServerError
+		err = do()
+		if !errors.Is(err, errDefault) || !errors.Is(err, ErrApiCall) || err.Error() != "error on foo api call: oups" {
+			t.Fatalf("got %v, want %v", err, errDefault)
+		}
+	})
+}
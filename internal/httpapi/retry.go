@@ -0,0 +1,189 @@
+package httpapi
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	MAX_ATTEMPTS      = 4
+	BASE_DELAY        = 500 * time.Millisecond
+	MAX_DELAY         = 10 * time.Second
+	BREAKER_THRESHOLD = 5
+	BREAKER_COOLDOWN  = 30 * time.Second
+)
+
+var ErrCircuitOpen = errors.New("api calls suspended after repeated failures")
+
+// Breaker stops calling an api failing again and again: after Threshold
+// failures in a row the calls fail right away with ErrCircuitOpen for
+// Cooldown, then a single call is let through to probe the api.
+type Breaker struct {
+	// Threshold and Cooldown default to BREAKER_THRESHOLD and
+	// BREAKER_COOLDOWN when left empty.
+	Threshold int
+	Cooldown  time.Duration
+
+	mutex    sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (b *Breaker) threshold() int {
+	if b.Threshold > 0 {
+		return b.Threshold
+	}
+	return BREAKER_THRESHOLD
+}
+
+func (b *Breaker) cooldown() time.Duration {
+	if b.Cooldown > 0 {
+		return b.Cooldown
+	}
+	return BREAKER_COOLDOWN
+}
+
+// Allow returns ErrCircuitOpen while the api is left alone.
+func (b *Breaker) Allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures < b.threshold() {
+		return nil
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown() {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// Record counts the outcome of a call let through by Allow.
+func (b *Breaker) Record(failed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold() {
+		b.openedAt = time.Now()
+	}
+}
+
+// Retry sends again the requests failing for a transient reason: a network
+// error, a 5xx or a 429 answer. Only the requests safe to send twice are
+// sent again, any method but POST and PATCH or a request carrying
+// IdempotencyHeader.
+type Retry struct {
+	// MaxAttempts, BaseDelay and MaxDelay default to MAX_ATTEMPTS,
+	// BASE_DELAY and MAX_DELAY when left empty. A Retry-After longer than
+	// MaxDelay is not waited for, the error is returned instead.
+	MaxAttempts       int
+	BaseDelay         time.Duration
+	MaxDelay          time.Duration
+	IdempotencyHeader string
+	// Breaker is shared by every request to the api, none when nil.
+	Breaker *Breaker
+}
+
+func (r *Retry) maxAttempts() int {
+	if r.MaxAttempts > 0 {
+		return r.MaxAttempts
+	}
+	return MAX_ATTEMPTS
+}
+
+func (r *Retry) maxDelay() time.Duration {
+	if r.MaxDelay > 0 {
+		return r.MaxDelay
+	}
+	return MAX_DELAY
+}
+
+// backoff doubles the delay for every attempt made, then picks it at random
+// between its half and itself so clients don't retry all at once.
+func (r *Retry) backoff(attempts int) time.Duration {
+	delay := r.BaseDelay
+	if delay <= 0 {
+		delay = BASE_DELAY
+	}
+	for i := 1; i < attempts && delay < r.maxDelay(); i++ {
+		delay *= 2
+	}
+	delay = min(delay, r.maxDelay())
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (r *Retry) replayable(request *http.Request) bool {
+	if request.Body != nil && request.GetBody == nil {
+		return false
+	}
+	if request.Method == http.MethodPost || request.Method == http.MethodPatch {
+		return r.IdempotencyHeader != "" && request.Header.Get(r.IdempotencyHeader) != ""
+	}
+	return true
+}
+
+// isOutage tells if err means the api can't be reached or is failing.
+func isOutage(err error) bool {
+	var urlErr *url.Error
+	return errors.Is(err, ErrUnavailable) || errors.As(err, &urlErr)
+}
+
+// Do sends request through apiErrors.Do, as many times as needed and
+// allowed.
+func (r *Retry) Do(apiErrors Errors, client *http.Client, request *http.Request) (response *http.Response, err error) {
+	ctx := request.Context()
+	for attempt := 1; ; attempt++ {
+		if r.Breaker != nil {
+			err = r.Breaker.Allow()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		response, err = apiErrors.Do(client, request)
+		outage := isOutage(err) && ctx.Err() == nil
+		if r.Breaker != nil {
+			r.Breaker.Record(outage)
+		}
+		if err == nil || attempt >= r.maxAttempts() || !r.replayable(request) {
+			return
+		}
+		if !outage && !errors.Is(err, ErrRateLimited) {
+			return
+		}
+
+		delay := r.backoff(attempt)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+			if statusErr.RetryAfter > r.maxDelay() {
+				return
+			}
+			delay = statusErr.RetryAfter
+		}
+
+		if request.GetBody != nil {
+			request.Body, err = request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	apiErrors := Errors{Default: NewError("error on foo api call", ErrApiCall), Unauthorized: NewError("unauthorized foo access", ErrUnauthorized)}
+
+	// newServer answers the statuses in turn then 200, recording the bodies received
+	newServer := func(t testing.TB, header http.Header, statuses ...int) (*httptest.Server, *[]string) {
+		t.Helper()
+		var bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(body))
+			for name, values := range header {
+				rw.Header()[name] = values
+			}
+			if len(bodies) <= len(statuses) {
+				rw.WriteHeader(statuses[len(bodies)-1])
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server, &bodies
+	}
+
+	do := func(retry *Retry, server *httptest.Server, method string, header string) error {
+		request, _ := http.NewRequest(method, server.URL, strings.NewReader("payload"))
+		if header != "" {
+			request.Header.Set("X-Request-Id", header)
+		}
+		response, err := retry.Do(apiErrors, server.Client(), request)
+		if err == nil {
+			response.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("It should retry transient failures with the same body", func(t *testing.T) {
+		server, bodies := newServer(t, nil, http.StatusBadGateway, http.StatusServiceUnavailable)
+		retry := &Retry{BaseDelay: time.Millisecond}
+
+		err := do(retry, server, http.MethodPut, "")
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+		if len(*bodies) != 3 || (*bodies)[2] != "payload" {
+			t.Fatalf("unexpected attempts %q", *bodies)
+		}
+	})
+
+	t.Run("It should only retry a POST carrying the idempotency header", func(t *testing.T) {
+		server, bodies := newServer(t, nil, http.StatusInternalServerError, http.StatusInternalServerError)
+		retry := &Retry{BaseDelay: time.Millisecond, IdempotencyHeader: "X-Request-Id"}
+
+		err := do(retry, server, http.MethodPost, "")
+		if !errors.Is(err, ErrUnavailable) || !errors.Is(err, ErrApiCall) || len(*bodies) != 1 {
+			t.Fatalf("got %v after %d attempts, want a single attempt", err, len(*bodies))
+		}
+		if err := do(retry, server, http.MethodPost, "42"); err != nil || len(*bodies) != 3 {
+			t.Fatalf("got %v after %d attempts, want a success", err, len(*bodies))
+		}
+	})
+
+	t.Run("It should not wait for a Retry-After too long", func(t *testing.T) {
+		server, bodies := newServer(t, http.Header{"Retry-After": {"120"}}, http.StatusTooManyRequests)
+
+		err := do(&Retry{}, server, http.MethodGet, "")
+		var statusErr *StatusError
+		if !errors.Is(err, ErrRateLimited) || !errors.As(err, &statusErr) || statusErr.RetryAfter != 2*time.Minute || len(*bodies) != 1 {
+			t.Fatalf("got %v after %d attempts", err, len(*bodies))
+		}
+
+		date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+		if delay := retryAfter(date); delay < 59*time.Minute || delay > time.Hour {
+			t.Fatalf("got %s for %s", delay, date)
+		}
+	})
+
+	t.Run("It should not retry other errors", func(t *testing.T) {
+		server, bodies := newServer(t, nil, http.StatusBadRequest, http.StatusUnauthorized)
+		retry := &Retry{BaseDelay: time.Millisecond}
+
+		if err := do(retry, server, http.MethodGet, ""); err == nil || len(*bodies) != 1 {
+			t.Fatalf("got %v after %d attempts", err, len(*bodies))
+		}
+		if err := do(retry, server, http.MethodGet, ""); !errors.Is(err, ErrUnauthorized) || len(*bodies) != 2 {
+			t.Fatalf("got %v after %d attempts", err, len(*bodies))
+		}
+	})
+
+	t.Run("It should stop waiting when the context is done", func(t *testing.T) {
+		server, _ := newServer(t, nil, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		_, err := (&Retry{BaseDelay: time.Hour, MaxDelay: time.Hour}).Do(apiErrors, server.Client(), request)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("It should open the circuit after repeated failures", func(t *testing.T) {
+		server, bodies := newServer(t, nil, http.StatusInternalServerError, http.StatusInternalServerError)
+		breaker := &Breaker{Threshold: 2, Cooldown: 20 * time.Millisecond}
+		retry := &Retry{MaxAttempts: 1, Breaker: breaker}
+
+		do(retry, server, http.MethodGet, "")
+		do(retry, server, http.MethodGet, "")
+		if err := do(retry, server, http.MethodGet, ""); !errors.Is(err, ErrCircuitOpen) || len(*bodies) != 2 {
+			t.Fatalf("got %v after %d calls, want %v", err, len(*bodies), ErrCircuitOpen)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		if err := do(retry, server, http.MethodGet, ""); err != nil {
+			t.Fatalf("the probe should have gone through: %v", err)
+		}
+		if err := do(retry, server, http.MethodGet, ""); err != nil {
+			t.Fatalf("the circuit should be closed again: %v", err)
+		}
+	})
+}
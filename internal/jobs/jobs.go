@@ -0,0 +1,365 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/helpers"
+)
+
+type Kind string
+
+const (
+	KindAdd    Kind = "add"
+	KindRemove Kind = "remove"
+)
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusDead    Status = "dead"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrNotDead     = errors.New("job is not dead-lettered")
+)
+
+type Job struct {
+	Id        string `json:"id"`
+	Kind      Kind   `json:"kind"`
+	GuildId   string `json:"guild_id"`
+	ChannelId string `json:"channel_id"`
+	MessageId string `json:"message_id"`
+	EmojiId   string `json:"emoji_id,omitempty"`
+	EmojiName string `json:"emoji_name"`
+	// Sink is the sink the job works on, a reaction queues a job per sink.
+	Sink        string    `json:"sink"`
+	Status      Status    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultPolicy = Policy{
+	MaxAttempts: 8,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    time.Hour,
+}
+
+// Backoff doubles the delay for every attempt already made, up to MaxDelay.
+func (p Policy) Backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, the job goes straight to the
+// dead-letter list.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// Journal persists jobs until they succeed or are discarded. The file is
+// read again before every operation so changes made by the admin command
+// are seen by a running bot, and every operation holds an exclusive lock on
+// a sidecar file so the bot and the admin command don't overwrite each other.
+type Journal struct {
+	Policy Policy
+
+	path    string
+	mutex   sync.Mutex
+	jobs    map[string]Job
+	claimed map[string]bool
+}
+
+func Open(path string) (journal *Journal, err error) {
+	journal = &Journal{Policy: DefaultPolicy, path: path, jobs: map[string]Job{}, claimed: map[string]bool{}}
+	err = journal.load()
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// lock takes the journal mutex, then an exclusive lock on the path.lock file
+// shared with the other processes using the journal.
+func (j *Journal) lock() (unlock func(), err error) {
+	j.mutex.Lock()
+	if j.path == "" {
+		return j.mutex.Unlock, nil
+	}
+
+	file, err := os.OpenFile(j.path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		j.mutex.Unlock()
+		return nil, err
+	}
+	err = lockFile(file)
+	if err != nil {
+		file.Close()
+		j.mutex.Unlock()
+		return nil, fmt.Errorf("lock journal %s: %w", j.path, err)
+	}
+	return func() {
+		unlockFile(file)
+		file.Close()
+		j.mutex.Unlock()
+	}, nil
+}
+
+func (j *Journal) load() (err error) {
+	if j.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+
+	var jobs []Job
+	err = json.Unmarshal(data, &jobs)
+	if err != nil {
+		return fmt.Errorf("invalid journal %s: %w", j.path, err)
+	}
+
+	j.jobs = make(map[string]Job, len(jobs))
+	for _, job := range jobs {
+		j.jobs[job.Id] = job
+	}
+	return
+}
+
+func (j *Journal) persist() (err error) {
+	if j.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(j.sorted(func(Job) bool { return true }), "", "  ")
+	if err != nil {
+		return
+	}
+	return helpers.WriteFileAtomic(j.path, data)
+}
+
+func (j *Journal) sorted(match func(Job) bool) (jobs []Job) {
+	for _, job := range j.jobs {
+		if match(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].CreatedAt.Before(jobs[b].CreatedAt) })
+	return
+}
+
+func newId() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Add persists a new job and claims it for the caller, who is expected to
+// run it right away or to Release it.
+func (j *Journal) Add(job Job) (added Job, err error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = j.load()
+	if err != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	job.Id = newId()
+	job.Status = StatusPending
+	job.CreatedAt = now
+	job.NextAttempt = now
+	j.jobs[job.Id] = job
+	j.claimed[job.Id] = true
+
+	return job, j.persist()
+}
+
+// Claim returns the pending jobs due at now that nobody is running yet.
+func (j *Journal) Claim(now time.Time) (jobs []Job, err error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = j.load()
+	if err != nil {
+		return
+	}
+
+	jobs = j.sorted(func(job Job) bool {
+		return job.Status == StatusPending && !j.claimed[job.Id] && !job.NextAttempt.After(now)
+	})
+	for _, job := range jobs {
+		j.claimed[job.Id] = true
+	}
+	return
+}
+
+func (j *Journal) Release(id string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	delete(j.claimed, id)
+}
+
+func (j *Journal) Complete(id string) (err error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = j.load()
+	if err != nil {
+		return
+	}
+
+	delete(j.claimed, id)
+	delete(j.jobs, id)
+	return j.persist()
+}
+
+// Fail records the failed attempt and schedules the next one, the job is
+// dead-lettered once the policy gives up or when jobErr is permanent.
+func (j *Journal) Fail(id string, jobErr error) (job Job, err error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = j.load()
+	if err != nil {
+		return
+	}
+
+	delete(j.claimed, id)
+	job, ok := j.jobs[id]
+	if !ok {
+		return job, ErrJobNotFound
+	}
+
+	job.Attempts++
+	job.LastError = jobErr.Error()
+	if IsPermanent(jobErr) || job.Attempts >= j.Policy.MaxAttempts {
+		job.Status = StatusDead
+	} else {
+		job.NextAttempt = time.Now().UTC().Add(j.Policy.Backoff(job.Attempts))
+	}
+	j.jobs[id] = job
+
+	return job, j.persist()
+}
+
+func (j *Journal) Dead() (jobs []Job, err error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = j.load()
+	if err != nil {
+		return
+	}
+	return j.sorted(func(job Job) bool { return job.Status == StatusDead }), nil
+}
+
+// Retry moves a dead-lettered job back to the pending ones with a fresh
+// attempts count.
+func (j *Journal) Retry(id string) (err error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = j.load()
+	if err != nil {
+		return
+	}
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if job.Status != StatusDead {
+		return ErrNotDead
+	}
+
+	job.Status = StatusPending
+	job.Attempts = 0
+	job.NextAttempt = time.Now().UTC()
+	j.jobs[id] = job
+	return j.persist()
+}
+
+func (j *Journal) Discard(id string) (err error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	err = j.load()
+	if err != nil {
+		return
+	}
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if job.Status != StatusDead {
+		return ErrNotDead
+	}
+
+	delete(j.jobs, id)
+	return j.persist()
+}
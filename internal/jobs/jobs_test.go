@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	assertError := func(t testing.TB, got, want error) {
+		t.Helper()
+		if got == nil {
+			t.Fatal("didn't get an error but wanted one")
+		}
+
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	job := Job{Kind: KindAdd, ChannelId: "channel", MessageId: "message", EmojiName: "👍"}
+	oops := errors.New("oops")
+
+	t.Run("It should compute exponential backoff up to the max delay", func(t *testing.T) {
+		policy := Policy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+		expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+		for i, want := range expected {
+			if got := policy.Backoff(i + 1); got != want {
+				t.Fatalf("attempt %d: got %s, want %s", i+1, got, want)
+			}
+		}
+	})
+
+	t.Run("It should keep jobs across restarts until completed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "journal.json")
+		journal, err := Open(path)
+		assertNoError(t, err)
+
+		added, err := journal.Add(job)
+		assertNoError(t, err)
+
+		reopened, err := Open(path)
+		assertNoError(t, err)
+		claimed, err := reopened.Claim(time.Now())
+		assertNoError(t, err)
+		if len(claimed) != 1 || claimed[0].Id != added.Id {
+			t.Fatalf("got %v, want job %s", claimed, added.Id)
+		}
+
+		assertNoError(t, reopened.Complete(added.Id))
+		again, err := Open(path)
+		assertNoError(t, err)
+		claimed, err = again.Claim(time.Now())
+		assertNoError(t, err)
+		if len(claimed) != 0 {
+			t.Fatalf("completed job should be gone but got %v", claimed)
+		}
+	})
+
+	t.Run("It should not claim a job twice", func(t *testing.T) {
+		journal, err := Open("")
+		assertNoError(t, err)
+
+		added, err := journal.Add(job)
+		assertNoError(t, err)
+		claimed, err := journal.Claim(time.Now())
+		assertNoError(t, err)
+		if len(claimed) != 0 {
+			t.Fatalf("job claimed on add should not be claimed again but got %v", claimed)
+		}
+
+		journal.Release(added.Id)
+		claimed, err = journal.Claim(time.Now())
+		assertNoError(t, err)
+		if len(claimed) != 1 {
+			t.Fatalf("released job should be claimed but got %v", claimed)
+		}
+	})
+
+	t.Run("It should schedule a retry with backoff on failure", func(t *testing.T) {
+		journal, err := Open("")
+		assertNoError(t, err)
+		journal.Policy = Policy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+		added, err := journal.Add(job)
+		assertNoError(t, err)
+		failed, err := journal.Fail(added.Id, oops)
+		assertNoError(t, err)
+
+		if failed.Status != StatusPending || failed.Attempts != 1 || failed.LastError != "oops" {
+			t.Fatalf("unexpected job after failure %+v", failed)
+		}
+		claimed, _ := journal.Claim(time.Now())
+		if len(claimed) != 0 {
+			t.Fatal("job should not be due before its backoff")
+		}
+		claimed, _ = journal.Claim(time.Now().Add(2 * time.Minute))
+		if len(claimed) != 1 {
+			t.Fatal("job should be due after its backoff")
+		}
+	})
+
+	t.Run("It should dead-letter a job after max attempts or on permanent error", func(t *testing.T) {
+		journal, err := Open("")
+		assertNoError(t, err)
+		journal.Policy = Policy{MaxAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+		first, _ := journal.Add(job)
+		journal.Fail(first.Id, oops)
+		failed, err := journal.Fail(first.Id, oops)
+		assertNoError(t, err)
+		if failed.Status != StatusDead {
+			t.Fatalf("got status %s, want %s", failed.Status, StatusDead)
+		}
+
+		second, _ := journal.Add(job)
+		failed, err = journal.Fail(second.Id, Permanent(oops))
+		assertNoError(t, err)
+		if failed.Status != StatusDead || failed.Attempts != 1 {
+			t.Fatalf("permanent error should dead-letter right away but got %+v", failed)
+		}
+
+		dead, err := journal.Dead()
+		assertNoError(t, err)
+		if len(dead) != 2 {
+			t.Fatalf("got %d dead jobs, want 2", len(dead))
+		}
+	})
+
+	t.Run("It should retry or discard dead-lettered jobs", func(t *testing.T) {
+		journal, err := Open(filepath.Join(t.TempDir(), "journal.json"))
+		assertNoError(t, err)
+
+		first, _ := journal.Add(job)
+		journal.Fail(first.Id, Permanent(oops))
+		second, _ := journal.Add(job)
+		journal.Fail(second.Id, Permanent(oops))
+
+		assertNoError(t, journal.Retry(first.Id))
+		assertNoError(t, journal.Discard(second.Id))
+		assertError(t, journal.Discard(first.Id), ErrNotDead)
+		assertError(t, journal.Retry("unknown"), ErrJobNotFound)
+
+		claimed, _ := journal.Claim(time.Now())
+		if len(claimed) != 1 || claimed[0].Id != first.Id || claimed[0].Attempts != 0 {
+			t.Fatalf("retried job should be pending again but got %v", claimed)
+		}
+		dead, _ := journal.Dead()
+		if len(dead) != 0 {
+			t.Fatalf("got %d dead jobs, want 0", len(dead))
+		}
+	})
+
+	t.Run("It should detect permanent errors through wrapping", func(t *testing.T) {
+		if !IsPermanent(Permanent(oops)) || IsPermanent(oops) {
+			t.Fatal("permanent error not detected properly")
+		}
+		if !errors.Is(Permanent(oops), oops) {
+			t.Fatal("permanent error should wrap the original one")
+		}
+	})
+
+	t.Run("It should not lose jobs written by another process", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "journal.json")
+		bot, err := Open(path)
+		assertNoError(t, err)
+		admin, err := Open(path)
+		assertNoError(t, err)
+
+		var wait sync.WaitGroup
+		for _, journal := range []*Journal{bot, admin, bot, admin} {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				for range 10 {
+					_, err := journal.Add(job)
+					if err != nil {
+						t.Errorf("got an error but didn't want one: %q", err)
+					}
+				}
+			}()
+		}
+		wait.Wait()
+
+		reopened, err := Open(path)
+		assertNoError(t, err)
+		claimed, err := reopened.Claim(time.Now())
+		assertNoError(t, err)
+		if len(claimed) != 40 {
+			t.Fatalf("got %d jobs, want 40", len(claimed))
+		}
+	})
+}
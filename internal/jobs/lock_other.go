@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+
+package jobs
+
+import "os"
+
+// lockFile is a no-op where the system has no file lock: the journal is only
+// guarded by its mutex, so the dead-letter commands must not be run while the
+// bot is running.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
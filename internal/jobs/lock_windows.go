@@ -0,0 +1,40 @@
+//go:build windows
+
+package jobs
+
+import (
+	"math"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const LOCKFILE_EXCLUSIVE_LOCK = 0x2
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+// lockFile locks the whole file through LockFileEx, waiting for the other
+// processes to release it.
+func lockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+	ok, _, err := procLockFileEx.Call(file.Fd(), LOCKFILE_EXCLUSIVE_LOCK, 0,
+		math.MaxUint32, math.MaxUint32, uintptr(unsafe.Pointer(&overlapped)))
+	if ok == 0 {
+		return err
+	}
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+	ok, _, err := procUnlockFileEx.Call(file.Fd(), 0,
+		math.MaxUint32, math.MaxUint32, uintptr(unsafe.Pointer(&overlapped)))
+	if ok == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package reactions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type Action string
+
+const (
+	ActionCreate             Action = "create"
+	ActionCreateWithPriority Action = "create-with-priority"
+	ActionSnooze             Action = "snooze"
+	ActionMarkRead           Action = "mark-read"
+	ActionDrop               Action = "drop"
+)
+
+var (
+	ErrEmptyEmoji      = errors.New("reaction rule must define an emoji")
+	ErrUnknownAction   = errors.New("unknown reaction action")
+	ErrInvalidPriority = errors.New("priority must be between 1 and 4")
+	ErrInvalidSnooze   = errors.New("snooze_days must be greater than 0")
+	ErrDuplicateEmoji  = errors.New("emoji is mapped more than once")
+	ErrEmptySink       = errors.New("sink name can't be empty")
+)
+
+type Rule struct {
+	Emoji      string `json:"emoji"`
+	Action     Action `json:"action"`
+	Priority   int    `json:"priority,omitempty"`
+	SnoozeDays int    `json:"snooze_days,omitempty"`
+	// Sinks names the sinks the rule applies to, all of them when empty.
+	Sinks []string `json:"sinks,omitempty"`
+}
+
+type file struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Mapping resolves a Discord reaction to the rule configured for it.
+// Custom guild emojis can be mapped by ID or by name, unicode emojis are
+// matched without their skin-tone modifier.
+type Mapping struct {
+	rules map[string]Rule
+}
+
+func (r Rule) IsCreate() bool {
+	return r.Action == ActionCreate || r.Action == ActionCreateWithPriority || r.Action == ActionSnooze
+}
+
+func (r Rule) validate() (err error) {
+	if r.Emoji == "" {
+		return ErrEmptyEmoji
+	}
+	for _, name := range r.Sinks {
+		if name == "" {
+			return fmt.Errorf("%w for %s", ErrEmptySink, r.Emoji)
+		}
+	}
+
+	switch r.Action {
+	case ActionCreate, ActionMarkRead, ActionDrop:
+	case ActionCreateWithPriority:
+		if r.Priority < 1 || r.Priority > 4 {
+			return fmt.Errorf("%w for %s", ErrInvalidPriority, r.Emoji)
+		}
+	case ActionSnooze:
+		if r.SnoozeDays <= 0 {
+			return fmt.Errorf("%w for %s", ErrInvalidSnooze, r.Emoji)
+		}
+	default:
+		return fmt.Errorf("%w %q for %s", ErrUnknownAction, r.Action, r.Emoji)
+	}
+	return
+}
+
+// StripSkinTone removes the Fitzpatrick modifiers (U+1F3FB to U+1F3FF)
+// so every skin-tone variant resolves to its base emoji.
+func StripSkinTone(emoji string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 0x1F3FB && r <= 0x1F3FF {
+			return -1
+		}
+		return r
+	}, emoji)
+}
+
+func New(rules []Rule) (mapping Mapping, err error) {
+	mapping.rules = make(map[string]Rule, len(rules))
+
+	for _, rule := range rules {
+		err = rule.validate()
+		if err != nil {
+			return Mapping{}, err
+		}
+
+		key := StripSkinTone(rule.Emoji)
+		if _, exist := mapping.rules[key]; exist {
+			return Mapping{}, fmt.Errorf("%w: %s", ErrDuplicateEmoji, rule.Emoji)
+		}
+		mapping.rules[key] = rule
+	}
+	return
+}
+
+func Default() Mapping {
+	mapping, _ := New([]Rule{
+		{Emoji: "😍", Action: ActionCreate},
+		{Emoji: "👌", Action: ActionCreate},
+		{Emoji: "👍", Action: ActionCreate},
+		{Emoji: "✅", Action: ActionCreate},
+	})
+	return mapping
+}
+
+func Load(path string) (mapping Mapping, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var content file
+	err = json.Unmarshal(data, &content)
+	if err != nil {
+		return mapping, fmt.Errorf("invalid reactions file %s: %w", path, err)
+	}
+
+	return New(content.Rules)
+}
+
+func (m Mapping) Lookup(id, name string) (rule Rule, ok bool) {
+	if id != "" {
+		rule, ok = m.rules[id]
+		if ok {
+			return
+		}
+	}
+	rule, ok = m.rules[StripSkinTone(name)]
+	return
+}
+
+// Sinks returns every sink named by the rules.
+func (m Mapping) Sinks() (names []string) {
+	seen := map[string]bool{}
+	for _, rule := range m.rules {
+		for _, name := range rule.Sinks {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return
+}
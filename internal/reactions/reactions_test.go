@@ -0,0 +1,111 @@
+package reactions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMapping(t *testing.T) {
+	assertRule := func(t testing.TB, mapping Mapping, id, name string, want Action) {
+		t.Helper()
+		rule, ok := mapping.Lookup(id, name)
+		if !ok {
+			t.Fatalf("no rule found for %q (%q)", name, id)
+		}
+		if rule.Action != want {
+			t.Fatalf("got %q, want %q", rule.Action, want)
+		}
+	}
+
+	t.Run("It should map default emojis to create", func(t *testing.T) {
+		mapping := Default()
+		for _, emoji := range []string{"😍", "👌", "👍", "✅"} {
+			assertRule(t, mapping, "", emoji, ActionCreate)
+		}
+		if _, ok := mapping.Lookup("", "😂"); ok {
+			t.Fatal("unknown emoji should not be mapped")
+		}
+	})
+
+	t.Run("It should match skin-tone variants as the base emoji", func(t *testing.T) {
+		mapping := Default()
+		assertRule(t, mapping, "", "👍🏽", ActionCreate)
+		assertRule(t, mapping, "", "👌🏿", ActionCreate)
+	})
+
+	t.Run("It should match custom emojis by id or by name", func(t *testing.T) {
+		mapping, err := New([]Rule{
+			{Emoji: "123456789", Action: ActionDrop},
+			{Emoji: "pepe_read", Action: ActionMarkRead},
+		})
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+
+		assertRule(t, mapping, "123456789", "renamed", ActionDrop)
+		assertRule(t, mapping, "987654321", "pepe_read", ActionMarkRead)
+	})
+
+	t.Run("It should reject invalid rules", func(t *testing.T) {
+		tests := []struct {
+			rule Rule
+			want error
+		}{
+			{rule: Rule{Action: ActionCreate}, want: ErrEmptyEmoji},
+			{rule: Rule{Emoji: "👍", Action: "unknown"}, want: ErrUnknownAction},
+			{rule: Rule{Emoji: "👍", Action: ActionCreateWithPriority, Priority: 5}, want: ErrInvalidPriority},
+			{rule: Rule{Emoji: "👍", Action: ActionSnooze}, want: ErrInvalidSnooze},
+			{rule: Rule{Emoji: "👍", Action: ActionCreate, Sinks: []string{""}}, want: ErrEmptySink},
+		}
+
+		for _, test := range tests {
+			_, err := New([]Rule{test.rule})
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want %v", err, test.want)
+			}
+		}
+	})
+
+	t.Run("It should reject emoji mapped twice", func(t *testing.T) {
+		_, err := New([]Rule{
+			{Emoji: "👍", Action: ActionCreate},
+			{Emoji: "👍🏻", Action: ActionDrop},
+		})
+		if !errors.Is(err, ErrDuplicateEmoji) {
+			t.Fatalf("got %v, want %v", err, ErrDuplicateEmoji)
+		}
+	})
+
+	t.Run("It should load mapping from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "reactions.json")
+		content := `{"rules": [
+			{"emoji": "⭐", "action": "create-with-priority", "priority": 4},
+			{"emoji": "⏰", "action": "snooze", "snooze_days": 7, "sinks": ["todoist", "markdown"]},
+			{"emoji": "📚", "action": "create", "sinks": ["wallabag", "todoist"]}
+		]}`
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatal("can't write reactions file")
+		}
+
+		mapping, err := Load(path)
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+
+		rule, _ := mapping.Lookup("", "⭐")
+		if rule.Priority != 4 {
+			t.Fatalf("got priority %d, want 4", rule.Priority)
+		}
+		rule, _ = mapping.Lookup("", "⏰")
+		if rule.SnoozeDays != 7 {
+			t.Fatalf("got snooze_days %d, want 7", rule.SnoozeDays)
+		}
+		if got := strings.Join(mapping.Sinks(), ","); got != "markdown,todoist,wallabag" {
+			t.Fatalf("got sinks %q, want markdown,todoist,wallabag", got)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package schedule
+
+import "time"
+
+const (
+	DATE_FORMAT         = "2006-01-02"
+	MAX_PER_DAY         = 5
+	MAX_DAYS_TO_LOOK_UP = 30
+)
+
+// CountFunc returns the number of slots already taken on date.
+type CountFunc func(date string) (count int, err error)
+
+type Capacity struct {
+	MaxPerDay       int
+	MaxDaysToLookUp int
+}
+
+// WithDefaults fills the fields of c left empty with MAX_PER_DAY and
+// MAX_DAYS_TO_LOOK_UP.
+func (c Capacity) WithDefaults() Capacity {
+	return c.Or(Capacity{MaxPerDay: MAX_PER_DAY, MaxDaysToLookUp: MAX_DAYS_TO_LOOK_UP})
+}
+
+// Or fills the fields of c left empty from defaults.
+func (c Capacity) Or(defaults Capacity) Capacity {
+	if c.MaxPerDay == 0 {
+		c.MaxPerDay = defaults.MaxPerDay
+	}
+	if c.MaxDaysToLookUp == 0 {
+		c.MaxDaysToLookUp = defaults.MaxDaysToLookUp
+	}
+	return c
+}
+
+// FirstFreeDay looks for the first day from start with enough room for
+// slots. A day with nothing scheduled always fits, even when slots is above
+// the max per day.
+func (c Capacity) FirstFreeDay(start time.Time, slots int, count CountFunc) (date string, err error) {
+	date = start.Format(DATE_FORMAT)
+
+	for i := 1; i <= c.MaxDaysToLookUp; i++ {
+		taken, err := count(date)
+		if err != nil {
+			return "", err
+		}
+		if taken == 0 || taken+slots <= c.MaxPerDay {
+			return date, nil
+		}
+		date = start.AddDate(0, 0, i).Format(DATE_FORMAT)
+	}
+
+	return
+}
+
+// StartDate parses date as the first day a news can be scheduled on,
+// today when empty.
+func StartDate(date string) (start time.Time, err error) {
+	if date == "" {
+		return time.Now(), nil
+	}
+	return time.ParseInLocation(DATE_FORMAT, date, time.Local)
+}
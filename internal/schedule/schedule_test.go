@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCapacity(t *testing.T) {
+	assertEqualString := func(t testing.TB, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	start := time.Date(2026, 1, 30, 0, 0, 0, 0, time.Local)
+	capacity := Capacity{MaxPerDay: 2, MaxDaysToLookUp: 5}
+	taken := map[string]int{"2026-01-30": 2, "2026-01-31": 1}
+	count := func(date string) (int, error) { return taken[date], nil }
+
+	t.Run("It should skip full days", func(t *testing.T) {
+		date, err := capacity.FirstFreeDay(start, 1, count)
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+		assertEqualString(t, date, "2026-01-31")
+	})
+
+	t.Run("It should look for room for every slot", func(t *testing.T) {
+		date, _ := capacity.FirstFreeDay(start, 2, count)
+		assertEqualString(t, date, "2026-02-01")
+	})
+
+	t.Run("It should fit a group bigger than a day on an empty day", func(t *testing.T) {
+		date, _ := capacity.FirstFreeDay(start, 4, count)
+		assertEqualString(t, date, "2026-02-01")
+	})
+
+	t.Run("It should return the count error", func(t *testing.T) {
+		oops := errors.New("oops")
+		_, err := capacity.FirstFreeDay(start, 1, func(string) (int, error) { return 0, oops })
+		if err != oops {
+			t.Fatalf("got %v, want %v", err, oops)
+		}
+	})
+
+	t.Run("It should parse the start date", func(t *testing.T) {
+		date, err := StartDate("2026-01-30")
+		if err != nil || !date.Equal(start) {
+			t.Fatalf("got %s (%v), want %s", date, err, start)
+		}
+		_, err = StartDate("30/01/2026")
+		if err == nil {
+			t.Fatal("didn't get an error but wanted one")
+		}
+	})
+
+	t.Run("It should fill the empty fields with the defaults", func(t *testing.T) {
+		got := Capacity{MaxPerDay: 2}.WithDefaults()
+		want := Capacity{MaxPerDay: 2, MaxDaysToLookUp: MAX_DAYS_TO_LOOK_UP}
+		if got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is an in-memory TaskSink meant for tests. Every operation returns
+// Err when it is set.
+type Memory struct {
+	Err error
+
+	mutex  sync.Mutex
+	nextId int
+	items  map[string]Item
+}
+
+func NewMemory() *Memory {
+	return &Memory{items: map[string]Item{}}
+}
+
+func (m *Memory) add(item Item) Item {
+	m.nextId++
+	item.Id = fmt.Sprint(m.nextId)
+	item.Status = StatusOpen
+	if item.DueDate == "" {
+		item.DueDate = time.Now().Format("2006-01-02")
+	}
+	children := item.Children
+	item.Children = nil
+	m.items[item.Id] = item
+
+	for _, child := range children {
+		if m.findByUrl(child.Url) != nil {
+			continue
+		}
+		child.ParentId = item.Id
+		child.DueDate = item.DueDate
+		child.Priority = item.Priority
+		item.Children = append(item.Children, m.add(child))
+	}
+	return item
+}
+
+func (m *Memory) findByUrl(url string) *Item {
+	for _, item := range m.items {
+		if url != "" && item.Url == url && item.Status == StatusOpen {
+			return &item
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Create(ctx context.Context, item Item) (created Item, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Err != nil {
+		return created, m.Err
+	}
+	if m.findByUrl(item.Url) != nil {
+		return created, ErrAlreadyExist
+	}
+	if len(item.Children) > 0 {
+		exist := 0
+		for _, child := range item.Children {
+			if m.findByUrl(child.Url) != nil {
+				exist++
+			}
+		}
+		if exist == len(item.Children) {
+			return created, ErrAlreadyExist
+		}
+	}
+	return m.add(item), nil
+}
+
+func (m *Memory) FindByUrl(ctx context.Context, url string) (item Item, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Err != nil {
+		return item, m.Err
+	}
+	found := m.findByUrl(url)
+	if found == nil {
+		return item, ErrNotFound
+	}
+	return *found, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id string) (err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.items[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.items, id)
+	for childId, child := range m.items {
+		if child.ParentId == id {
+			delete(m.items, childId)
+		}
+	}
+	return
+}
+
+func (m *Memory) Complete(ctx context.Context, id string) (err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	item, ok := m.items[id]
+	if !ok {
+		return ErrNotFound
+	}
+	item.Status = StatusDone
+	m.items[id] = item
+	return
+}
+
+func (m *Memory) Reschedule(ctx context.Context, id string, dueDate string) (err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	item, ok := m.items[id]
+	if !ok {
+		return ErrNotFound
+	}
+	item.DueDate = dueDate
+	m.items[id] = item
+	return
+}
+
+func (m *Memory) List(ctx context.Context) (items []Item, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	for _, item := range m.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if len(items[i].Id) != len(items[j].Id) {
+			return len(items[i].Id) < len(items[j].Id)
+		}
+		return items[i].Id < items[j].Id
+	})
+	return
+}
@@ -0,0 +1,83 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	assertError := func(t testing.TB, got, want error) {
+		t.Helper()
+		if got == nil {
+			t.Fatal("didn't get an error but wanted one")
+		}
+
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	ctx := context.Background()
+
+	t.Run("It should not save the same url twice while open", func(t *testing.T) {
+		memory := NewMemory()
+		created, err := memory.Create(ctx, Item{Title: "foo", Url: "https://foo.bar"})
+		assertNoError(t, err)
+
+		_, err = memory.Create(ctx, Item{Title: "foo", Url: "https://foo.bar"})
+		assertError(t, err, ErrAlreadyExist)
+
+		assertNoError(t, memory.Complete(ctx, created.Id))
+		_, err = memory.FindByUrl(ctx, "https://foo.bar")
+		assertError(t, err, ErrNotFound)
+		_, err = memory.Create(ctx, Item{Title: "foo", Url: "https://foo.bar"})
+		assertNoError(t, err)
+	})
+
+	t.Run("It should create and delete a group with its children", func(t *testing.T) {
+		memory := NewMemory()
+		parent, err := memory.Create(ctx, Item{Title: "digest", DueDate: "2026-01-02", Children: []Item{
+			{Title: "a", Url: "https://a.dev"},
+			{Title: "b", Url: "https://b.dev"},
+		}})
+		assertNoError(t, err)
+		if len(parent.Children) != 2 || parent.Children[0].ParentId != parent.Id || parent.Children[1].DueDate != "2026-01-02" {
+			t.Fatalf("unexpected group %+v", parent)
+		}
+
+		assertNoError(t, memory.Delete(ctx, parent.Id))
+		items, err := memory.List(ctx)
+		assertNoError(t, err)
+		if len(items) != 0 {
+			t.Fatalf("got %v, want no items", items)
+		}
+	})
+
+	t.Run("It should reschedule an item", func(t *testing.T) {
+		memory := NewMemory()
+		created, _ := memory.Create(ctx, Item{Title: "foo", Url: "https://foo.bar"})
+
+		assertNoError(t, memory.Reschedule(ctx, created.Id, "2026-03-04"))
+		item, _ := memory.FindByUrl(ctx, "https://foo.bar")
+		if item.DueDate != "2026-03-04" {
+			t.Fatalf("got due date %q, want %q", item.DueDate, "2026-03-04")
+		}
+		assertError(t, memory.Reschedule(ctx, "unknown", "2026-03-04"), ErrNotFound)
+	})
+
+	t.Run("It should return the configured error", func(t *testing.T) {
+		memory := NewMemory()
+		memory.Err = errors.New("oops")
+
+		_, err := memory.List(ctx)
+		assertError(t, err, memory.Err)
+	})
+}
@@ -0,0 +1,88 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound     = errors.New("item not found")
+	ErrAlreadyExist = errors.New("item already exist")
+	// ErrPermanent matches the errors retrying has no chance to fix, like a
+	// wrong configuration or credentials.
+	ErrPermanent    = errors.New("permanent sink error")
+	ErrNotSupported = NewPermanentError("operation not supported by this sink")
+)
+
+// permanentError is an error of its own also matched by errors.Is against
+// ErrPermanent.
+type permanentError struct {
+	message string
+}
+
+func (e *permanentError) Error() string {
+	return e.message
+}
+
+func (e *permanentError) Is(target error) bool {
+	return target == ErrPermanent
+}
+
+// NewPermanentError returns an error with message that retrying can't fix.
+func NewPermanentError(message string) error {
+	return &permanentError{message: message}
+}
+
+// AlreadyReadError is returned by Create when the url was saved and done
+// already, on Date.
+type AlreadyReadError struct {
+	Url  string
+	Date string
+}
+
+func (e *AlreadyReadError) Error() string {
+	return fmt.Sprintf("%s already read on %s", e.Url, e.Date)
+}
+
+type Status string
+
+const (
+	StatusOpen Status = "open"
+	StatusDone Status = "done"
+)
+
+// Item is a saved news. On Create, DueDate is the first day the item can be
+// scheduled on (today when empty) and Children turns the item into a group
+// with one subtask per child. Channel is the discord channel the news was
+// shared in, Emoji the reaction saving it and Tags are set by the bot for
+// the sinks supporting them.
+type Item struct {
+	Id       string
+	ParentId string
+	Title    string
+	Url      string
+	Channel  string
+	Emoji    string
+	Tags     []string
+	DueDate  string
+	Priority int
+	Status   Status
+	Children []Item
+}
+
+// TaskSink is a place news are saved to. Create returns ErrAlreadyExist when
+// the url is already saved, FindByUrl returns ErrNotFound when it is not.
+type TaskSink interface {
+	Create(ctx context.Context, item Item) (created Item, err error)
+	FindByUrl(ctx context.Context, url string) (item Item, err error)
+	Delete(ctx context.Context, id string) error
+	Reschedule(ctx context.Context, id string, dueDate string) error
+	List(ctx context.Context) (items []Item, err error)
+}
+
+// Completer is implemented by the sinks able to mark an item as done
+// instead of deleting it.
+type Completer interface {
+	Complete(ctx context.Context, id string) error
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/helpers"
+)
+
+type Status string
+
+const (
+	StatusCreated Status = "created"
+	StatusClosed  Status = "closed"
+	StatusDeleted Status = "deleted"
+	StatusFailed  Status = "failed"
+)
+
+var ErrMissingKey = errors.New("record must have a message id, an url and a sink")
+
+type Record struct {
+	GuildId      string    `json:"guild_id"`
+	ChannelId    string    `json:"channel_id"`
+	MessageId    string    `json:"message_id"`
+	Url          string    `json:"url"`
+	Sink         string    `json:"sink"`
+	TaskId       string    `json:"task_id"`
+	ParentTaskId string    `json:"parent_task_id,omitempty"`
+	Status       Status    `json:"status"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Store keeps the link between Discord messages and the tasks created from
+// them. Every write atomically rewrites the whole file so a crash never
+// leaves a half written store behind.
+// An empty path keeps the records in memory only.
+type Store struct {
+	path    string
+	mutex   sync.RWMutex
+	records map[string]Record
+}
+
+// key identifies a record by message, link and sink.
+func key(messageId, url, sink string) string {
+	return messageId + "|" + url + "|" + sink
+}
+
+func (r Record) key() string {
+	return key(r.MessageId, r.Url, r.Sink)
+}
+
+func Open(path string) (store *Store, err error) {
+	store = &Store{path: path, records: map[string]Record{}}
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		store.records[record.key()] = record
+	}
+	return
+}
+
+func (s *Store) persist() (err error) {
+	if s.path == "" {
+		return
+	}
+
+	records := make([]Record, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].key() < records[j].key()
+	})
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return helpers.WriteFileAtomic(s.path, data)
+}
+
+func (s *Store) Put(record Record) (err error) {
+	if record.MessageId == "" || record.Url == "" || record.Sink == "" {
+		return ErrMissingKey
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record.UpdatedAt = time.Now().UTC()
+	s.records[record.key()] = record
+	return s.persist()
+}
+
+func (s *Store) Get(messageId, url, sink string) (record Record, ok bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	record, ok = s.records[key(messageId, url, sink)]
+	return
+}
+
+func (s *Store) ByMessage(messageId string) (records []Record) {
+	return s.filter(func(record Record) bool { return record.MessageId == messageId })
+}
+
+func (s *Store) ByUrl(url string) (records []Record) {
+	return s.filter(func(record Record) bool { return record.Url == url })
+}
+
+// ByTask returns the records of the task taskId of sink.
+func (s *Store) ByTask(sink, taskId string) (records []Record) {
+	return s.filter(func(record Record) bool { return record.Sink == sink && record.TaskId == taskId })
+}
+
+func (s *Store) filter(match func(Record) bool) (records []Record) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, record := range s.records {
+		if match(record) {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Url < records[j].Url })
+	return
+}
+
+func (s *Store) Delete(messageId, url, sink string) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.records, key(messageId, url, sink))
+	return s.persist()
+}
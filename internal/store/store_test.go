@@ -0,0 +1,121 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	assertEqualString := func(t testing.TB, got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	record := Record{
+		GuildId:   "guild",
+		ChannelId: "channel",
+		MessageId: "message",
+		Url:       "https://foo.bar",
+		Sink:      "todoist",
+		TaskId:    "12345",
+		Status:    StatusCreated,
+	}
+
+	t.Run("It should put and get a record", func(t *testing.T) {
+		store, err := Open("")
+		assertNoError(t, err)
+
+		assertNoError(t, store.Put(record))
+		got, ok := store.Get(record.MessageId, record.Url, record.Sink)
+		if !ok {
+			t.Fatal("record should exist")
+		}
+		assertEqualString(t, got.TaskId, record.TaskId)
+		if got.UpdatedAt.IsZero() {
+			t.Fatal("updated at should be set")
+		}
+	})
+
+	t.Run("It should reject record without key", func(t *testing.T) {
+		store, err := Open("")
+		assertNoError(t, err)
+
+		for _, missing := range []Record{{Url: "https://foo.bar", Sink: "todoist"}, {MessageId: "message", Url: "https://foo.bar"}} {
+			err = store.Put(missing)
+			if err != ErrMissingKey {
+				t.Fatalf("got %v, want %v", err, ErrMissingKey)
+			}
+		}
+	})
+
+	t.Run("It should survive a restart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "store.json")
+		store, err := Open(path)
+		assertNoError(t, err)
+		assertNoError(t, store.Put(record))
+
+		reopened, err := Open(path)
+		assertNoError(t, err)
+		got, ok := reopened.Get(record.MessageId, record.Url, record.Sink)
+		if !ok {
+			t.Fatal("record should exist after restart")
+		}
+		assertEqualString(t, string(got.Status), string(StatusCreated))
+	})
+
+	t.Run("It should list records by message and by url", func(t *testing.T) {
+		store, err := Open("")
+		assertNoError(t, err)
+
+		other := record
+		other.Url = "https://bar.foo"
+		assertNoError(t, store.Put(record))
+		assertNoError(t, store.Put(other))
+
+		if got := store.ByMessage(record.MessageId); len(got) != 2 {
+			t.Fatalf("got %d records, want 2", len(got))
+		}
+		if got := store.ByUrl(other.Url); len(got) != 1 {
+			t.Fatalf("got %d records, want 1", len(got))
+		}
+	})
+
+	t.Run("It should keep a record per sink", func(t *testing.T) {
+		store, err := Open("")
+		assertNoError(t, err)
+
+		other := record
+		other.Sink = "markdown"
+		other.TaskId = "note"
+		assertNoError(t, store.Put(record))
+		assertNoError(t, store.Put(other))
+
+		got, _ := store.Get(record.MessageId, record.Url, record.Sink)
+		assertEqualString(t, got.TaskId, record.TaskId)
+		got, _ = store.Get(record.MessageId, record.Url, "markdown")
+		assertEqualString(t, got.TaskId, "note")
+	})
+
+	t.Run("It should delete a record", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "store.json")
+		store, err := Open(path)
+		assertNoError(t, err)
+		assertNoError(t, store.Put(record))
+		assertNoError(t, store.Delete(record.MessageId, record.Url, record.Sink))
+
+		reopened, err := Open(path)
+		assertNoError(t, err)
+		if _, ok := reopened.Get(record.MessageId, record.Url, record.Sink); ok {
+			t.Fatal("record should have been deleted")
+		}
+	})
+}
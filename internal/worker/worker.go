@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+var (
+	ErrQueueFull = errors.New("worker queue is full, try again later")
+	ErrStopped   = errors.New("worker pool is stopped")
+)
+
+type Job func()
+
+// Pool runs jobs on a fixed number of workers. Jobs sharing a key always go
+// to the same worker so they run one after the other, in submission order.
+type Pool struct {
+	queues  []chan Job
+	wg      sync.WaitGroup
+	mutex   sync.RWMutex
+	stopped bool
+}
+
+// New starts concurrency workers sharing queueSize pending jobs.
+func New(concurrency, queueSize int) *Pool {
+	concurrency = max(concurrency, 1)
+	capacity := max(queueSize/concurrency, 1)
+
+	pool := &Pool{queues: make([]chan Job, concurrency)}
+	for i := range pool.queues {
+		pool.queues[i] = make(chan Job, capacity)
+		pool.wg.Add(1)
+		go pool.work(pool.queues[i])
+	}
+	return pool
+}
+
+func (p *Pool) work(queue chan Job) {
+	defer p.wg.Done()
+	for job := range queue {
+		job()
+	}
+}
+
+func (p *Pool) queueFor(key string) chan Job {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return p.queues[hash.Sum32()%uint32(len(p.queues))]
+}
+
+// Submit enqueues the job without blocking, ErrQueueFull is returned when
+// the worker in charge of key has no room left.
+func (p *Pool) Submit(key string, job Job) (err error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if p.stopped {
+		return ErrStopped
+	}
+
+	select {
+	case p.queueFor(key) <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Drain stops accepting jobs and waits for the queued ones to finish or for
+// ctx to be done.
+func (p *Pool) Drain(ctx context.Context) (err error) {
+	p.mutex.Lock()
+	if !p.stopped {
+		p.stopped = true
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	}
+	p.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
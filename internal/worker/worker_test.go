@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	assertError := func(t testing.TB, got, want error) {
+		t.Helper()
+		if got == nil {
+			t.Fatal("didn't get an error but wanted one")
+		}
+
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	assertNoError := func(t testing.TB, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("got an error but didn't want one: %q", err)
+		}
+	}
+
+	t.Run("It should run every submitted job before drain returns", func(t *testing.T) {
+		pool := New(4, 100)
+		var count atomic.Int32
+
+		for i := 0; i < 50; i++ {
+			assertNoError(t, pool.Submit(string(rune('a'+i%10)), func() { count.Add(1) }))
+		}
+
+		assertNoError(t, pool.Drain(context.Background()))
+		if count.Load() != 50 {
+			t.Fatalf("got %d jobs run, want 50", count.Load())
+		}
+	})
+
+	t.Run("It should serialize jobs sharing a key", func(t *testing.T) {
+		pool := New(4, 100)
+		var mutex sync.Mutex
+		var running, overlaps int
+		var order []int
+
+		for i := 0; i < 20; i++ {
+			assertNoError(t, pool.Submit("message", func() {
+				mutex.Lock()
+				running++
+				if running > 1 {
+					overlaps++
+				}
+				order = append(order, i)
+				mutex.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+			}))
+		}
+		assertNoError(t, pool.Drain(context.Background()))
+
+		if overlaps != 0 {
+			t.Fatalf("jobs with the same key overlapped %d times", overlaps)
+		}
+		for i, got := range order {
+			if got != i {
+				t.Fatalf("jobs ran out of order: %v", order)
+			}
+		}
+	})
+
+	t.Run("It should apply back-pressure when the queue is full", func(t *testing.T) {
+		pool := New(1, 1)
+		release := make(chan struct{})
+
+		assertNoError(t, pool.Submit("a", func() { <-release }))
+		// wait for the worker to pick the blocking job
+		for len(pool.queues[0]) != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		assertNoError(t, pool.Submit("a", func() {}))
+		assertError(t, pool.Submit("a", func() {}), ErrQueueFull)
+
+		close(release)
+		assertNoError(t, pool.Drain(context.Background()))
+	})
+
+	t.Run("It should refuse jobs once drained", func(t *testing.T) {
+		pool := New(1, 1)
+		assertNoError(t, pool.Drain(context.Background()))
+		assertError(t, pool.Submit("a", func() {}), ErrStopped)
+	})
+
+	t.Run("It should stop waiting on drain deadline", func(t *testing.T) {
+		pool := New(1, 1)
+		release := make(chan struct{})
+		defer close(release)
+		assertNoError(t, pool.Submit("a", func() { <-release }))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assertError(t, pool.Drain(ctx), context.DeadlineExceeded)
+	})
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log"
 	"net/http"
@@ -10,22 +12,87 @@ import (
 	"time"
 
 	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/bot"
-	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/todoist"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/config"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/jobs"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/reactions"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/store"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/worker"
 )
 
-const DEFAULT_TIMEOUT = 10
-
 func main() {
-	var httpTimeout int
-	flag.IntVar(&httpTimeout, "timeout", DEFAULT_TIMEOUT, "http client timeout")
-	bot := bot.Bot{Todo: todoist.Todoist{Client: &http.Client{Timeout: time.Duration(httpTimeout) * time.Second}}}
-	err := bot.Start()
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if len(args) > 0 && args[0] == "dead-letter" {
+		err := runDeadLetter(cfg.JournalPath, args[1:])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "migrate-labels" {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		err := runMigrateLabels(ctx, cfg, args[1:])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	mapping := reactions.Default()
+	if cfg.ReactionsFile != "" {
+		mapping, err = reactions.Load(cfg.ReactionsFile)
+		if err != nil {
+			log.Fatalln("could not load reactions", err)
+		}
+	}
+
+	messageStore, err := store.Open(cfg.StorePath)
+	if err != nil {
+		log.Fatalln("could not open store", err)
+	}
+
+	journal, err := jobs.Open(cfg.JournalPath)
+	if err != nil {
+		log.Fatalln("could not open journal", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	client := &http.Client{Timeout: time.Duration(cfg.HttpTimeout) * time.Second}
+	sinks, err := newSinks(ctx, cfg, client)
 	if err != nil {
-		log.Fatalln("bot could not start", err)
+		log.Fatalln("could not initialize backend", err)
 	}
 
-	log.Println("bot is now running.\nPress CTRL-C to exit.")
-	sc := make(chan os.Signal, 1)
-	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	<-sc
+	bot := bot.Bot{
+		Token:           cfg.DiscordToken,
+		Client:          client,
+		Sinks:           sinks,
+		Reactions:       mapping,
+		Store:           messageStore,
+		Pool:            worker.New(cfg.Workers, cfg.QueueSize),
+		Journal:         journal,
+		CloseOnRemove:   cfg.CloseOnRemove,
+		ShutdownTimeout: time.Duration(cfg.ShutdownTimeout) * time.Second,
+		ReactionTimeout: time.Duration(cfg.ReactionTimeout) * time.Second,
+	}
+	log.Println("Press CTRL-C to exit.")
+	err = bot.Run(ctx)
+	if err != nil {
+		log.Fatalln("bot stopped with error", err)
+	}
 }
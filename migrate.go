@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/config"
+)
+
+var ErrMigrateLabelsUsage = errors.New("usage: migrate-labels, with the todoist api key configured")
+
+// runMigrateLabels strips the date and title labels the former versions put
+// on the open todos of the todoist project.
+func runMigrateLabels(ctx context.Context, cfg config.Config, args []string) (err error) {
+	if len(args) > 0 || cfg.Todoist.ApiKey == "" {
+		return ErrMigrateLabelsUsage
+	}
+
+	todo := newTodoist(cfg, &http.Client{Timeout: time.Duration(cfg.HttpTimeout) * time.Second})
+	todo.Bootstrap = false
+	err = todo.InitContext(ctx, cfg.Todoist.ProjectName)
+	if err != nil {
+		return
+	}
+
+	migration, err := todo.MigrateLabels(ctx)
+	if err != nil {
+		return
+	}
+	log.Printf("todoist labels of %s: %s, completed todos are skipped", cfg.Todoist.ProjectName, migration)
+	return
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/caldav"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/github"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/linkding"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/markdown"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/todoist"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/cmd/wallabag"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/config"
+	"github.com/ludovicalarcon/aza-discord-news-sorter/internal/sink"
+)
+
+// newSinks builds the backends chosen in the configuration, named after
+// their backend. Their initialization stops when ctx is done.
+func newSinks(ctx context.Context, cfg config.Config, client *http.Client) (sinks map[string]sink.TaskSink, err error) {
+	sinks = map[string]sink.TaskSink{}
+	for _, backend := range cfg.Backends() {
+		sinks[backend], err = newSink(ctx, cfg, backend, client)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", backend, err)
+		}
+	}
+	return
+}
+
+func todoistRoutes(routes []config.Route) (todoistRoutes []todoist.Route) {
+	for _, route := range routes {
+		todoistRoutes = append(todoistRoutes, todoist.Route(route))
+	}
+	return
+}
+
+// newTodoist configures the todoist backend, it is left to initialize.
+func newTodoist(cfg config.Config, client *http.Client) *todoist.Todoist {
+	return &todoist.Todoist{
+		Client:            client,
+		ApiKey:            cfg.Todoist.ApiKey,
+		ApiVersion:        cfg.Todoist.ApiVersion,
+		MaxTodoPerDay:     cfg.Schedule.MaxTodoPerDay,
+		MaxDaysToLookUp:   cfg.Schedule.MaxDaysToLookUp,
+		SlotPerLink:       cfg.Schedule.SlotPerLink,
+		SyncMode:          cfg.Todoist.Sync,
+		SnapshotInterval:  time.Duration(cfg.Todoist.SnapshotInterval) * time.Second,
+		CompletedLookback: time.Duration(cfg.Todoist.CompletedLookbackDays) * 24 * time.Hour,
+		Bootstrap:         cfg.Todoist.Bootstrap,
+		Sections:          cfg.Todoist.Sections,
+		Labels:            cfg.Todoist.Labels,
+		Routes:            todoistRoutes(cfg.Todoist.Routes),
+		Retry:             todoist.NewRetry(),
+	}
+}
+
+func newSink(ctx context.Context, cfg config.Config, backend string, client *http.Client) (taskSink sink.TaskSink, err error) {
+	switch backend {
+	case config.BACKEND_TODOIST:
+		todo := newTodoist(cfg, client)
+		err = todo.InitContext(ctx, cfg.Todoist.ProjectName)
+		if err != nil {
+			return nil, err
+		}
+		return todo, nil
+	case config.BACKEND_MARKDOWN:
+		vault := &markdown.Vault{
+			Dir:             cfg.Markdown.Dir,
+			Mode:            cfg.Markdown.Mode,
+			MaxTodoPerDay:   cfg.Schedule.MaxTodoPerDay,
+			MaxDaysToLookUp: cfg.Schedule.MaxDaysToLookUp,
+			SlotPerLink:     cfg.Schedule.SlotPerLink,
+		}
+		err = vault.Init()
+		if err != nil {
+			return nil, err
+		}
+		return vault, nil
+	case config.BACKEND_CALDAV:
+		calendar := &caldav.CalDAV{
+			Client:          client,
+			CalendarUrl:     cfg.CalDAV.Url,
+			Username:        cfg.CalDAV.Username,
+			Password:        cfg.CalDAV.Password,
+			MaxTodoPerDay:   cfg.Schedule.MaxTodoPerDay,
+			MaxDaysToLookUp: cfg.Schedule.MaxDaysToLookUp,
+			SlotPerLink:     cfg.Schedule.SlotPerLink,
+		}
+		err = calendar.Init(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return calendar, nil
+	case config.BACKEND_WALLABAG:
+		readLater := &wallabag.Wallabag{
+			Client:       client,
+			BaseUrl:      cfg.Wallabag.Url,
+			ClientId:     cfg.Wallabag.ClientId,
+			ClientSecret: cfg.Wallabag.ClientSecret,
+			Username:     cfg.Wallabag.Username,
+			Password:     cfg.Wallabag.Password,
+		}
+		err = readLater.Init(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return readLater, nil
+	case config.BACKEND_LINKDING:
+		readLater := &linkding.Linkding{Client: client, BaseUrl: cfg.Linkding.Url, Token: cfg.Linkding.Token}
+		err = readLater.Init(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return readLater, nil
+	case config.BACKEND_GITHUB:
+		issues := &github.GitHub{
+			Client:           client,
+			BaseUrl:          cfg.GitHub.BaseUrl,
+			Token:            cfg.GitHub.Token,
+			Repository:       cfg.GitHub.Repository,
+			Label:            cfg.GitHub.Label,
+			MaxIssuesPerWeek: cfg.GitHub.MaxIssuesPerWeek,
+			MaxWeeksToLookUp: cfg.GitHub.MaxWeeksToLookUp,
+		}
+		err = issues.Init(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return issues, nil
+	case config.BACKEND_MEMORY:
+		return sink.NewMemory(), nil
+	}
+	return nil, fmt.Errorf("%w: unknown backend %q", config.ErrInvalidConfig, backend)
+}